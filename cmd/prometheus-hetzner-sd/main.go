@@ -2,17 +2,58 @@ package main
 
 import (
 	"os"
+	"path/filepath"
 
 	"github.com/joho/godotenv"
 	"github.com/promhippie/prometheus-hetzner-sd/pkg/command"
 )
 
+const (
+	// defaultEnvFile defines the env file which gets loaded if it exists.
+	defaultEnvFile = ".env"
+)
+
 func main() {
-	if env := os.Getenv("PROMETHEUS_HETZNER_ENV_FILE"); env != "" {
-		godotenv.Load(env)
-	}
+	loadEnv()
 
 	if err := command.Run(); err != nil {
 		os.Exit(1)
 	}
 }
+
+// loadEnv loads the variables of the default env file and all files listed
+// within PROMETHEUS_HETZNER_ENV_FILE. Later files override earlier ones, while
+// variables of the process environment still take precedence over all files.
+func loadEnv() {
+	files := make([]string, 0)
+
+	if _, err := os.Stat(defaultEnvFile); err == nil {
+		files = append(files, defaultEnvFile)
+	}
+
+	for _, file := range filepath.SplitList(os.Getenv("PROMETHEUS_HETZNER_ENV_FILE")) {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+
+	values := make(map[string]string)
+
+	for _, file := range files {
+		vars, err := godotenv.Read(file)
+
+		if err != nil {
+			continue
+		}
+
+		for key, val := range vars {
+			values[key] = val
+		}
+	}
+
+	for key, val := range values {
+		if _, ok := os.LookupEnv(key); !ok {
+			os.Setenv(key, val)
+		}
+	}
+}
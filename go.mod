@@ -5,10 +5,17 @@ go 1.15
 require (
 	github.com/appscode/go v0.0.0-20201105063637-5613f3b8169f // indirect
 	github.com/appscode/go-hetzner v0.0.0-20180411135907-c038e08b19b1
+	github.com/aws/aws-sdk-go v1.38.3
+	github.com/cenkalti/backoff v2.2.1+incompatible
 	github.com/cpuguy83/go-md2man/v2 v2.0.0 // indirect
+	github.com/fsnotify/fsnotify v1.4.9
 	github.com/go-chi/chi/v5 v5.0.3
-	github.com/go-kit/kit v0.10.0
+	github.com/go-kit/log v0.2.1
+	github.com/go-redis/redis/v8 v8.11.0
+	github.com/go-zookeeper/zk v1.0.2
 	github.com/joho/godotenv v1.3.0
+	github.com/miekg/dns v1.1.41
+	github.com/nats-io/nats.go v1.11.0
 	github.com/oklog/run v1.1.0
 	github.com/prometheus/client_golang v1.11.0
 	github.com/prometheus/common v0.29.0
@@ -16,5 +23,15 @@ require (
 	github.com/prometheus/prometheus v1.8.2-0.20210331101223-3cafc58827d1
 	github.com/russross/blackfriday/v2 v2.1.0 // indirect
 	github.com/urfave/cli/v2 v2.3.0
+	github.com/zalando/go-keyring v0.1.1
+	go.mozilla.org/sops/v3 v3.7.1
+	go.opentelemetry.io/otel v1.0.1
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp v1.0.1
+	go.opentelemetry.io/otel/sdk v1.0.1
+	go.opentelemetry.io/otel/trace v1.0.1
 	gopkg.in/yaml.v2 v2.4.0
+	gopkg.in/yaml.v3 v3.0.1
+	k8s.io/api v0.20.5
+	k8s.io/apimachinery v0.20.5
+	k8s.io/client-go v0.20.5
 )
@@ -42,6 +42,14 @@ func main() {
 				Help:    v.Usage,
 				List:    false,
 			})
+		case *cli.Float64Flag:
+			flags = append(flags, flag{
+				Flag:    v.Name,
+				Default: strconv.FormatFloat(v.Value, 'f', -1, 64),
+				Envs:    v.EnvVars,
+				Help:    v.Usage,
+				List:    false,
+			})
 		case *cli.BoolFlag:
 			flags = append(flags, flag{
 				Flag:    v.Name,
@@ -58,6 +66,14 @@ func main() {
 				Help:    v.Usage,
 				List:    false,
 			})
+		case *cli.GenericFlag:
+			flags = append(flags, flag{
+				Flag:    v.Name,
+				Default: v.Value.String(),
+				Envs:    v.EnvVars,
+				Help:    v.Usage,
+				List:    false,
+			})
 		case *cli.StringSliceFlag:
 			flags = append(flags, flag{
 				Flag:    v.Name,
@@ -66,6 +82,20 @@ func main() {
 				Help:    v.Usage,
 				List:    true,
 			})
+		case *cli.IntSliceFlag:
+			values := make([]string, 0)
+
+			for _, value := range v.Value.Value() {
+				values = append(values, strconv.Itoa(value))
+			}
+
+			flags = append(flags, flag{
+				Flag:    v.Name,
+				Default: strings.Join(values, ", "),
+				Envs:    v.EnvVars,
+				Help:    v.Usage,
+				List:    true,
+			})
 		default:
 			fmt.Printf("unknown type: %s\n", v)
 			os.Exit(1)
@@ -0,0 +1,178 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/appscode/go-hetzner"
+	"github.com/prometheus/common/model"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// account bundles the API client and the static labels of a project. If the
+// credentials could not be read the error is kept to fail the project only.
+type account struct {
+	client     *hetzner.Client
+	credential config.Credential
+	api        *apiClient
+	username   string
+	password   string
+	labels     model.LabelSet
+	err        error
+}
+
+// fetch lists the servers of the account and records the request metrics.
+func (a *account) fetch(ctx context.Context) ([]*hetzner.ServerSummary, error) {
+	ctx, span := tracer.Start(ctx, "list servers", trace.WithAttributes(
+		attribute.String("project", a.credential.Project),
+	))
+	defer span.End()
+
+	if a.err != nil {
+		failSpan(span, a.err)
+		return nil, a.err
+	}
+
+	now := time.Now()
+	servers, resp, err := listServers(ctx, a)
+	requestDuration.WithLabelValues(a.credential.Project).Observe(time.Since(now).Seconds())
+	observeRequest(a.credential.Project, "server", resp, err)
+	failSpan(span, err)
+
+	return servers, err
+}
+
+// subnets lists the subnets of the account and records the request metrics.
+func (a *account) subnets(ctx context.Context) (map[int][]subnet, error) {
+	ctx, span := tracer.Start(ctx, "list subnets", trace.WithAttributes(
+		attribute.String("project", a.credential.Project),
+	))
+	defer span.End()
+
+	if a.err != nil {
+		failSpan(span, a.err)
+		return nil, a.err
+	}
+
+	now := time.Now()
+	subnets, resp, err := listSubnets(ctx, a)
+	requestDuration.WithLabelValues(a.credential.Project).Observe(time.Since(now).Seconds())
+	observeRequest(a.credential.Project, "subnet", resp, err)
+	failSpan(span, err)
+
+	return subnets, err
+}
+
+// listServers requests the servers, the request gets canceled with the context.
+func listServers(ctx context.Context, a *account) ([]*hetzner.ServerSummary, *http.Response, error) {
+	records := make([]struct {
+		Server *hetzner.ServerSummary `json:"server"`
+	}, 0)
+
+	resp, err := a.api.get(ctx, a, "/server", &records)
+
+	if err != nil {
+		return nil, resp, err
+	}
+
+	result := make([]*hetzner.ServerSummary, 0, len(records))
+
+	for _, record := range records {
+		result = append(result, record.Server)
+	}
+
+	return result, resp, nil
+}
+
+func newAccount(credential config.Credential, api *apiClient) (*account, error) {
+	username, password, err := credential.Secrets()
+
+	if err != nil {
+		err = fmt.Errorf("failed to read credentials for project %s: %w", credential.Project, err)
+	}
+
+	a := &account{
+		client:     hetzner.NewClient(username, password),
+		credential: credential,
+		api:        api,
+		username:   username,
+		password:   password,
+		labels:     make(model.LabelSet, len(credential.Labels)),
+		err:        err,
+	}
+
+	a.client.BaseURL = api.endpoint
+
+	for key, value := range credential.Labels {
+		if !model.LabelName(key).IsValid() {
+			return nil, fmt.Errorf("invalid label name %q for project %s", key, credential.Project)
+		}
+
+		a.labels[model.LabelName(key)] = model.LabelValue(value)
+	}
+
+	return a, nil
+}
+
+func newAccounts(credentials []config.Credential, api *apiClient) (map[string]*account, []string, error) {
+	accounts := make(map[string]*account, len(credentials))
+	projects := make([]string, 0, len(credentials))
+
+	for _, credential := range credentials {
+		a, err := newAccount(credential, api)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		accounts[credential.Project] = a
+		projects = append(projects, credential.Project)
+	}
+
+	return accounts, projects, nil
+}
+
+// rotated returns the account of the project with rotated credentials, either
+// swapped by a reload in the meantime or read again from the secret files. If
+// the credentials have not been changed nil is returned.
+func (d *Discoverer) rotated(project string, previous *account) *account {
+	d.mutex.RLock()
+	current := d.accounts[project]
+	d.mutex.RUnlock()
+
+	if current != nil && current != previous {
+		return current
+	}
+
+	if len(previous.credential.Files()) == 0 {
+		return nil
+	}
+
+	next, err := newAccount(previous.credential, previous.api)
+
+	if err != nil || next.err != nil || (next.username == previous.username && next.password == previous.password) {
+		return nil
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.accounts[project] != previous {
+		return d.accounts[project]
+	}
+
+	accounts := make(map[string]*account, len(d.accounts))
+
+	for key, value := range d.accounts {
+		accounts[key] = value
+	}
+
+	accounts[project] = next
+	d.accounts = accounts
+
+	return next
+}
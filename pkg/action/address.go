@@ -0,0 +1,95 @@
+package action
+
+import (
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
+)
+
+var (
+	// hostnameRegexp defines the chars replaced within hostnames.
+	hostnameRegexp = regexp.MustCompile(`[^a-z0-9.-]+`)
+)
+
+// addresser formats the scrape address of the targets by a hostname, a port
+// or a template.
+type addresser struct {
+	port     int
+	hostname string
+	domain   string
+	template *template.Template
+}
+
+func (a *addresser) apply(group *targetgroup.Group) error {
+	addr := string(group.Labels[model.AddressLabel])
+
+	if a.template != nil {
+		result, err := renderTemplate(a.template, group.Labels)
+
+		if err != nil {
+			return err
+		}
+
+		addr = result
+	} else {
+		switch a.hostname {
+		case "name":
+			if name := dnsName(string(group.Labels[model.LabelName(Labels["name"])])); name != "" {
+				addr = name
+
+				if a.domain != "" {
+					addr = addr + "." + a.domain
+				}
+			}
+		case "rdns":
+			addr = newTargetData(group.Labels).RDNS()
+		}
+
+		if a.port > 0 {
+			addr = net.JoinHostPort(addr, strconv.Itoa(a.port))
+		}
+	}
+
+	group.Labels[model.AddressLabel] = model.LabelValue(addr)
+
+	for _, labels := range group.Targets {
+		labels[model.AddressLabel] = model.LabelValue(addr)
+	}
+
+	return nil
+}
+
+// dnsName converts a server name into a valid hostname.
+func dnsName(name string) string {
+	return strings.Trim(hostnameRegexp.ReplaceAllString(strings.ToLower(name), "-"), "-.")
+}
+
+func newAddresser(cfg config.Target) (*addresser, error) {
+	if cfg.Port <= 0 && cfg.Address == "" && cfg.Hostname == "" {
+		return nil, nil
+	}
+
+	a := &addresser{
+		port:     cfg.Port,
+		hostname: cfg.Hostname,
+		domain:   strings.Trim(cfg.Domain, "."),
+	}
+
+	if cfg.Address != "" {
+		tmpl, err := parseTemplate("address", cfg.Address)
+
+		if err != nil {
+			return nil, err
+		}
+
+		a.template = tmpl
+	}
+
+	return a, nil
+}
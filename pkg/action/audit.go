@@ -0,0 +1,129 @@
+package action
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
+)
+
+// auditTarget defines a single target within the audit log.
+type auditTarget struct {
+	Source  string `json:"source"`
+	Name    string `json:"name,omitempty"`
+	Address string `json:"address,omitempty"`
+}
+
+// auditRecord defines the changes of the targets of a project by a refresh.
+type auditRecord struct {
+	Time      time.Time     `json:"time"`
+	Project   string        `json:"project"`
+	Trigger   string        `json:"trigger"`
+	Refresh   string        `json:"refresh,omitempty"`
+	Added     []auditTarget `json:"added,omitempty"`
+	Removed   []auditTarget `json:"removed,omitempty"`
+	Relabeled []auditTarget `json:"relabeled,omitempty"`
+}
+
+// auditLog appends the changes of the targets as JSON lines to a file, which
+// gets rotated once it exceeds the maximum size.
+type auditLog struct {
+	file    string
+	maxSize int64
+	backups int
+	mutex   sync.Mutex
+}
+
+func newAuditLog(cfg config.Audit) *auditLog {
+	if cfg.File == "" {
+		return nil
+	}
+
+	return &auditLog{
+		file:    cfg.File,
+		maxSize: int64(cfg.MaxSize) * 1024 * 1024,
+		backups: cfg.MaxBackups,
+	}
+}
+
+// write appends the records to the audit log.
+func (a *auditLog) write(records []*auditRecord) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	content := make([]byte, 0)
+
+	for _, record := range records {
+		line, err := json.Marshal(record)
+
+		if err != nil {
+			return err
+		}
+
+		content = append(content, line...)
+		content = append(content, '\n')
+	}
+
+	if err := a.rotate(int64(len(content))); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(a.file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// rotate renames the file to numbered backups if the next write would exceed
+// the maximum size, the oldest backups beyond the limit get removed.
+func (a *auditLog) rotate(size int64) error {
+	if a.maxSize <= 0 {
+		return nil
+	}
+
+	info, err := os.Stat(a.file)
+
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if info.Size() == 0 || info.Size()+size <= a.maxSize {
+		return nil
+	}
+
+	if a.backups <= 0 {
+		return os.Remove(a.file)
+	}
+
+	if err := os.Remove(a.backup(a.backups)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	for i := a.backups - 1; i > 0; i-- {
+		if err := os.Rename(a.backup(i), a.backup(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return os.Rename(a.file, a.backup(1))
+}
+
+// backup returns the file name of the numbered backup.
+func (a *auditLog) backup(num int) string {
+	return fmt.Sprintf("%s.%d", a.file, num)
+}
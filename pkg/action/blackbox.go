@@ -0,0 +1,69 @@
+package action
+
+import (
+	"text/template"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
+)
+
+const (
+	// paramTarget defines the label for the probed target.
+	paramTarget = model.ParamLabelPrefix + "target"
+
+	// paramModule defines the label for the blackbox module.
+	paramModule = model.ParamLabelPrefix + "module"
+)
+
+// blackbox formats the targets for probing by the blackbox exporter.
+type blackbox struct {
+	target   *template.Template
+	module   string
+	exporter string
+}
+
+func (b *blackbox) apply(group *targetgroup.Group) error {
+	target, err := renderTemplate(b.target, group.Labels)
+
+	if err != nil {
+		return err
+	}
+
+	addr := target
+
+	if b.exporter != "" {
+		addr = b.exporter
+	}
+
+	group.Labels[model.AddressLabel] = model.LabelValue(addr)
+	group.Labels[paramTarget] = model.LabelValue(target)
+
+	if b.module != "" {
+		group.Labels[paramModule] = model.LabelValue(b.module)
+	}
+
+	for _, labels := range group.Targets {
+		labels[model.AddressLabel] = model.LabelValue(addr)
+	}
+
+	return nil
+}
+
+func newBlackbox(cfg config.Blackbox) (*blackbox, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	tmpl, err := parseTemplate("blackbox", cfg.Target)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &blackbox{
+		target:   tmpl,
+		module:   cfg.Module,
+		exporter: cfg.Exporter,
+	}, nil
+}
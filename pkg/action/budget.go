@@ -0,0 +1,255 @@
+package action
+
+import (
+	"sync"
+	"time"
+
+	"github.com/appscode/go-hetzner"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// budgets tracks the request budgets of all projects.
+	budgets = newBudgetTracker()
+
+	budgetRemainingDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "request_budget_remaining"),
+		"Estimated number of requests to the Hetzner API left within the current interval.",
+		[]string{"project"},
+		nil,
+	)
+
+	budgetThrottledDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "request_throttled"),
+		"Whether requests to the Hetzner API are paused until the rate limit resets.",
+		[]string{"project"},
+		nil,
+	)
+
+	budgetLimitDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "request_budget_limit"),
+		"Number of requests to the Hetzner API allowed within the interval.",
+		[]string{"project"},
+		nil,
+	)
+)
+
+// budget defines the requests of a project within the sliding interval. The
+// limit and interval reported by the API on exceeded rate limits take
+// precedence over the configured budget.
+type budget struct {
+	limit     int
+	interval  time.Duration
+	requests  []time.Time
+	exhausted time.Time
+}
+
+// remaining returns the estimated number of requests left at the given time.
+func (b *budget) remaining(now time.Time) int {
+	b.prune(now)
+
+	if !b.exhausted.IsZero() && now.Sub(b.exhausted) < b.interval {
+		return 0
+	}
+
+	if remaining := b.limit - len(b.requests); remaining > 0 {
+		return remaining
+	}
+
+	return 0
+}
+
+// reset returns the time until an exceeded rate limit resets at the given
+// time, it's zero if the rate limit has not been exceeded.
+func (b *budget) reset(now time.Time) time.Duration {
+	if b.exhausted.IsZero() {
+		return 0
+	}
+
+	if wait := b.exhausted.Add(b.interval).Sub(now); wait > 0 {
+		return wait
+	}
+
+	return 0
+}
+
+// prune drops all requests which are out of the interval.
+func (b *budget) prune(now time.Time) {
+	idx := 0
+
+	for idx < len(b.requests) && now.Sub(b.requests[idx]) >= b.interval {
+		idx++
+	}
+
+	b.requests = b.requests[idx:]
+}
+
+// budgetTracker implements a collector for the request budgets per project.
+type budgetTracker struct {
+	limit    int
+	interval time.Duration
+	projects map[string]*budget
+	mutex    sync.Mutex
+}
+
+func newBudgetTracker() *budgetTracker {
+	return &budgetTracker{
+		projects: make(map[string]*budget),
+	}
+}
+
+// configure sets the budget for all projects without a limit reported by the
+// API, a limit of zero disables the tracking.
+func (t *budgetTracker) configure(limit int, interval time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.limit = limit
+	t.interval = interval
+	t.projects = make(map[string]*budget)
+}
+
+// observe records a request of the project and adopts the limits reported by
+// the API if the rate limit has been exceeded.
+func (t *budgetTracker) observe(project string, err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+	current, ok := t.projects[project]
+
+	if !ok {
+		current = &budget{
+			limit:    t.limit,
+			interval: t.interval,
+		}
+
+		t.projects[project] = current
+	}
+
+	if apiErr, ok := err.(*hetzner.APIError); ok && apiErr.Code == "RATE_LIMIT_EXCEEDED" {
+		if apiErr.MaxRequest > 0 && apiErr.Interval > 0 {
+			current.limit = apiErr.MaxRequest
+			current.interval = time.Duration(apiErr.Interval) * time.Second
+		}
+
+		current.exhausted = now
+	}
+
+	if current.interval > 0 {
+		current.requests = append(current.requests, now)
+		current.prune(now)
+	}
+}
+
+// wait returns the time until the exceeded rate limit of the project resets,
+// until then no requests should be sent for the project.
+func (t *budgetTracker) wait(project string) time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	current, ok := t.projects[project]
+
+	if !ok {
+		return 0
+	}
+
+	return current.reset(time.Now())
+}
+
+// allows checks if the budget of the project leaves room for an additional
+// request, beside the requests to list the servers on every refresh within the
+// interval. Without a budget all requests are allowed.
+func (t *budgetTracker) allows(project string, refresh time.Duration) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	current, ok := t.projects[project]
+
+	if !ok || current.limit <= 0 || current.interval <= 0 {
+		return true
+	}
+
+	reserve := 1
+
+	if refresh > 0 {
+		reserve += int(current.interval / refresh)
+	}
+
+	return current.remaining(time.Now()) > reserve
+}
+
+// headroom returns the remaining requests, the limit and the interval of the
+// budget of the project, the limit is zero if no budget is tracked.
+func (t *budgetTracker) headroom(project string) (int, int, time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	current, ok := t.projects[project]
+
+	if !ok {
+		return t.limit, t.limit, t.interval
+	}
+
+	if current.limit <= 0 || current.interval <= 0 {
+		return 0, 0, 0
+	}
+
+	return current.remaining(time.Now()), current.limit, current.interval
+}
+
+// remove drops the budget of a project which is not discovered anymore.
+func (t *budgetTracker) remove(project string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.projects, project)
+}
+
+// Describe implements the prometheus collector interface.
+func (t *budgetTracker) Describe(ch chan<- *prometheus.Desc) {
+	ch <- budgetRemainingDesc
+	ch <- budgetThrottledDesc
+	ch <- budgetLimitDesc
+}
+
+// Collect implements the prometheus collector interface.
+func (t *budgetTracker) Collect(ch chan<- prometheus.Metric) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+
+	for project, current := range t.projects {
+		throttled := 0.0
+
+		if current.reset(now) > 0 {
+			throttled = 1.0
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			budgetThrottledDesc,
+			prometheus.GaugeValue,
+			throttled,
+			project,
+		)
+
+		if current.limit <= 0 || current.interval <= 0 {
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			budgetRemainingDesc,
+			prometheus.GaugeValue,
+			float64(current.remaining(now)),
+			project,
+		)
+
+		ch <- prometheus.MustNewConstMetric(
+			budgetLimitDesc,
+			prometheus.GaugeValue,
+			float64(current.limit),
+			project,
+		)
+	}
+}
@@ -0,0 +1,207 @@
+package action
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+)
+
+// logChanges logs the targets added, removed or relabeled since the previous
+// refresh, the changed labels are only logged on debug level. The initial
+// refresh is only logged as debug summary to not flood the logs on startup,
+// unless the labels of the previous run got restored from the state file.
+// If enabled the changes are also appended to the audit log per project.
+func (d *Discoverer) logChanges(ctx context.Context, targets []*targetgroup.Group) {
+	logger := d.refreshLogger(ctx)
+	next := make(map[string]model.LabelSet, len(targets))
+
+	for _, target := range targets {
+		if len(target.Targets) == 0 {
+			continue
+		}
+
+		next[target.Source] = target.Labels
+	}
+
+	previous := d.labels
+	d.labels = next
+
+	if previous == nil {
+		level.Debug(logger).Log(
+			"msg", "Discovered initial targets",
+			"count", len(next),
+		)
+
+		return
+	}
+
+	added, removed, changed := 0, 0, 0
+	records := newAuditRecords(d, triggerFrom(ctx), refreshFrom(ctx))
+
+	for _, source := range sortedSources(next) {
+		labels := next[source]
+		before, ok := previous[source]
+
+		if !ok {
+			added++
+			records.append(&records.project(labels).Added, source, labels)
+
+			level.Info(logger).Log(
+				"msg", "Target added",
+				"source", source,
+				"name", labels[model.LabelName(d.label("name"))],
+				"address", labels[model.AddressLabel],
+			)
+
+			continue
+		}
+
+		if before.Equal(labels) {
+			continue
+		}
+
+		changed++
+		records.append(&records.project(labels).Relabeled, source, labels)
+
+		level.Info(logger).Log(
+			"msg", "Target relabeled",
+			"source", source,
+			"name", labels[model.LabelName(d.label("name"))],
+		)
+
+		for _, name := range changedLabels(before, labels) {
+			level.Debug(logger).Log(
+				"msg", "Target label changed",
+				"source", source,
+				"label", name,
+				"old", before[name],
+				"new", labels[name],
+			)
+		}
+	}
+
+	for _, source := range sortedSources(previous) {
+		if _, ok := next[source]; ok {
+			continue
+		}
+
+		removed++
+		records.append(&records.project(previous[source]).Removed, source, previous[source])
+
+		level.Info(logger).Log(
+			"msg", "Target removed",
+			"source", source,
+			"name", previous[source][model.LabelName(d.label("name"))],
+			"address", previous[source][model.AddressLabel],
+		)
+	}
+
+	if added > 0 || removed > 0 || changed > 0 {
+		level.Info(logger).Log(
+			"msg", "Targets changed",
+			"added", added,
+			"removed", removed,
+			"relabeled", changed,
+		)
+	}
+
+	if d.audit == nil || len(records.list) == 0 {
+		return
+	}
+
+	if err := d.audit.write(records.list); err != nil {
+		level.Warn(logger).Log(
+			"msg", "Failed to write audit log",
+			"err", err,
+		)
+	}
+}
+
+// sortedSources returns the sources of the label sets in a stable order.
+func sortedSources(labels map[string]model.LabelSet) []string {
+	result := make([]string, 0, len(labels))
+
+	for source := range labels {
+		result = append(result, source)
+	}
+
+	sort.Strings(result)
+	return result
+}
+
+// changedLabels returns the names of all labels which differ between both sets.
+func changedLabels(before, after model.LabelSet) []model.LabelName {
+	result := make([]model.LabelName, 0)
+
+	for name, value := range after {
+		if old, ok := before[name]; !ok || old != value {
+			result = append(result, name)
+		}
+	}
+
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			result = append(result, name)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i] < result[j]
+	})
+
+	return result
+}
+
+// auditRecords collects the changes of a refresh per project.
+type auditRecords struct {
+	discoverer *Discoverer
+	trigger    string
+	refresh    string
+	time       time.Time
+	projects   map[string]*auditRecord
+	list       []*auditRecord
+}
+
+func newAuditRecords(d *Discoverer, trigger, refresh string) *auditRecords {
+	return &auditRecords{
+		discoverer: d,
+		trigger:    trigger,
+		refresh:    refresh,
+		time:       time.Now().UTC(),
+		projects:   make(map[string]*auditRecord),
+	}
+}
+
+// project returns the record for the project of the labels.
+func (r *auditRecords) project(labels model.LabelSet) *auditRecord {
+	project := string(labels[model.LabelName(r.discoverer.label("project"))])
+
+	if record, ok := r.projects[project]; ok {
+		return record
+	}
+
+	record := &auditRecord{
+		Time:    r.time,
+		Project: project,
+		Trigger: r.trigger,
+		Refresh: r.refresh,
+	}
+
+	r.projects[project] = record
+	r.list = append(r.list, record)
+
+	return record
+}
+
+// append adds the target to the list of changes of a record.
+func (r *auditRecords) append(list *[]auditTarget, source string, labels model.LabelSet) {
+	*list = append(*list, auditTarget{
+		Source:  source,
+		Name:    string(labels[model.LabelName(r.discoverer.label("name"))]),
+		Address: string(labels[model.AddressLabel]),
+	})
+}
@@ -0,0 +1,239 @@
+package action
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/appscode/go-hetzner"
+	"github.com/cenkalti/backoff"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
+)
+
+// apiClient defines the HTTP client and the retries shared by the accounts of
+// all projects, the requests are built by the Hetzner client of the accounts.
+type apiClient struct {
+	http     *http.Client
+	retry    config.Retry
+	endpoint string
+}
+
+// get sends a request bound to the context and decodes the response into the
+// value. Requests failed with a server error or a network error get retried,
+// all other errors are returned as API error.
+func (c *apiClient) get(ctx context.Context, a *account, path string, v interface{}) (*http.Response, error) {
+	req, err := a.client.NewRequest(http.MethodGet, strings.TrimPrefix(path, "/"), nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		resp *http.Response
+		body []byte
+	)
+
+	if err := backoff.Retry(func() error {
+		resp, body = nil, nil
+		current, err := c.http.Do(req.WithContext(ctx))
+
+		if err != nil {
+			return err
+		}
+
+		defer current.Body.Close()
+		content, err := ioutil.ReadAll(current.Body)
+
+		if err != nil {
+			return err
+		}
+
+		resp, body = current, content
+
+		if code := current.StatusCode; code == http.StatusInternalServerError || code >= http.StatusBadGateway {
+			return &hetzner.APIError{
+				Response: current,
+				Status:   code,
+				Message:  http.StatusText(code),
+			}
+		}
+
+		return nil
+	}, newRetrier(ctx, a.credential.Project, c.retry)); err != nil && resp == nil {
+		return nil, err
+	}
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp, decodeError(resp, body)
+	}
+
+	if v == nil {
+		return resp, nil
+	}
+
+	return resp, json.Unmarshal(body, v)
+}
+
+// decodeError builds the API error from the body of an erroneous response.
+func decodeError(resp *http.Response, body []byte) error {
+	record := struct {
+		Error *hetzner.APIError `json:"error"`
+	}{}
+
+	if err := json.Unmarshal(body, &record); err != nil || record.Error == nil {
+		return &hetzner.APIError{
+			Response: resp,
+			Status:   resp.StatusCode,
+			Message:  http.StatusText(resp.StatusCode),
+		}
+	}
+
+	record.Error.Response = resp
+	return record.Error
+}
+
+func newAPIClient(cfg config.Target) (*apiClient, error) {
+	endpoint, err := endpointURL(cfg.Endpoint)
+
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if cfg.Proxy.URL != "" {
+		proxy, err := proxyURL(cfg.Proxy)
+
+		if err != nil {
+			return nil, err
+		}
+
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	tlsConfig, err := newTLSConfig(cfg.TLS)
+
+	if err != nil {
+		return nil, err
+	}
+
+	transport.TLSClientConfig = tlsConfig
+	transport.MaxIdleConns = cfg.Transport.MaxIdle
+	transport.MaxIdleConnsPerHost = cfg.Transport.MaxIdlePerHost
+	transport.IdleConnTimeout = cfg.Transport.IdleTimeout.Duration()
+	transport.ForceAttemptHTTP2 = cfg.Transport.HTTP2
+
+	if !cfg.Transport.HTTP2 {
+		transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+	transport.ResponseHeaderTimeout = cfg.Timeouts.Read.Duration()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   cfg.Timeouts.Connect.Duration(),
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+
+	return &apiClient{
+		http: &http.Client{
+			Transport: transport,
+			Timeout:   cfg.Timeouts.Request.Duration(),
+		},
+		retry:    cfg.Retry,
+		endpoint: endpoint,
+	}, nil
+}
+
+// endpointURL validates the base URL of the API, it always ends with a slash
+// to resolve the paths of the requests relative to a path of a gateway.
+func endpointURL(value string) (string, error) {
+	if value == "" {
+		return hetzner.DefaultEndpoint + "/", nil
+	}
+
+	endpoint, err := url.Parse(value)
+
+	if err != nil {
+		return "", fmt.Errorf("invalid endpoint: %w", err)
+	}
+
+	if (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return "", fmt.Errorf("invalid endpoint %q", value)
+	}
+
+	if !strings.HasSuffix(endpoint.Path, "/") {
+		endpoint.Path = endpoint.Path + "/"
+	}
+
+	return endpoint.String(), nil
+}
+
+// proxyURL parses the URL of the proxy, the credentials embedded into the URL
+// get replaced by the configured credentials.
+func proxyURL(cfg config.Proxy) (*url.URL, error) {
+	proxy, err := url.Parse(cfg.URL)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy url: %w", err)
+	}
+
+	if proxy.Scheme == "" || proxy.Host == "" {
+		return nil, fmt.Errorf("invalid proxy url %q", cfg.URL)
+	}
+
+	if cfg.Username != "" {
+		proxy.User = url.UserPassword(cfg.Username, cfg.Password)
+	}
+
+	return proxy, nil
+}
+
+// newTLSConfig builds the TLS config with the CA bundle and the client
+// certificate, without any options the defaults of Go are used.
+func newTLSConfig(cfg config.TLS) (*tls.Config, error) {
+	result := &tls.Config{
+		InsecureSkipVerify: cfg.Insecure,
+	}
+
+	if cfg.CAFile != "" {
+		content, err := ioutil.ReadFile(cfg.CAFile)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca file: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+
+		if !pool.AppendCertsFromPEM(content) {
+			return nil, fmt.Errorf("no certificates found in ca file %s", cfg.CAFile)
+		}
+
+		result.RootCAs = pool
+	}
+
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		if cfg.CertFile == "" || cfg.KeyFile == "" {
+			return nil, errors.New("client certificate requires both cert and key file")
+		}
+
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+
+		result.Certificates = []tls.Certificate{cert}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,153 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/appscode/go-hetzner"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
+)
+
+var (
+	// ErrCredentialsInvalid defines the error if any credential check failed.
+	ErrCredentialsInvalid = errors.New("credentials check failed")
+)
+
+// credentialResult defines the result of checking a single project.
+type credentialResult struct {
+	project  string
+	status   string
+	servers  string
+	subnets  string
+	headroom string
+	message  string
+}
+
+// TestCredentials handles the credentials test sub-command, it performs a
+// read-only request per project and reports the results.
+func TestCredentials(cfg *config.Config, logger log.Logger, w io.Writer) error {
+	api, err := newAPIClient(cfg.Target)
+
+	if err != nil {
+		return err
+	}
+
+	accounts, projects, err := newAccounts(cfg.Target.Credentials, api)
+
+	if err != nil {
+		return err
+	}
+
+	budgets.configure(cfg.Target.Budget.Requests, cfg.Target.Budget.Interval.Duration())
+
+	failed := false
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROJECT\tSTATUS\tSERVERS\tSUBNETS\tRATE LIMIT\tMESSAGE")
+
+	for _, project := range projects {
+		result := testAccount(project, accounts[project])
+
+		if result.status != "ok" {
+			failed = true
+		}
+
+		level.Debug(logger).Log(
+			"msg", "Tested credentials",
+			"project", project,
+			"status", result.status,
+		)
+
+		fmt.Fprintf(
+			tw,
+			"%s\t%s\t%s\t%s\t%s\t%s\n",
+			result.project,
+			result.status,
+			result.servers,
+			result.subnets,
+			result.headroom,
+			result.message,
+		)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if failed {
+		return ErrCredentialsInvalid
+	}
+
+	return nil
+}
+
+// testAccount requests the servers and subnets of a project, the subnets are
+// only used to report the permission scope of the credentials. The headroom
+// is taken from the request budget after the requests of the test.
+func testAccount(project string, acc *account) (result credentialResult) {
+	result = credentialResult{
+		project:  project,
+		status:   "ok",
+		servers:  "-",
+		subnets:  "-",
+		headroom: "-",
+	}
+
+	defer func() {
+		if remaining, limit, interval := budgets.headroom(project); limit > 0 {
+			result.headroom = fmt.Sprintf("%d/%d per %s", remaining, limit, shortDuration(interval))
+		}
+	}()
+
+	servers, err := acc.fetch(context.Background())
+
+	if err != nil {
+		result.status = "failed"
+		result.message = err.Error()
+
+		if apiErr, ok := err.(*hetzner.APIError); ok {
+			switch {
+			case apiErr.Status == 401:
+				result.message = "invalid username or password"
+			case apiErr.Code == "RATE_LIMIT_EXCEEDED":
+				result.status = "limited"
+				result.message = "rate limit exceeded"
+			}
+		}
+
+		return result
+	}
+
+	result.servers = strconv.Itoa(len(servers))
+
+	if _, err := acc.subnets(context.Background()); err != nil {
+		result.subnets = "denied"
+	} else {
+		result.subnets = "allowed"
+	}
+
+	return result
+}
+
+// shortDuration formats the duration without trailing zero units, like 1h
+// instead of 1h0m0s.
+func shortDuration(d time.Duration) string {
+	result := d.String()
+
+	if strings.HasSuffix(result, "m0s") {
+		result = strings.TrimSuffix(result, "0s")
+	}
+
+	if strings.HasSuffix(result, "h0m") {
+		result = strings.TrimSuffix(result, "0m")
+	}
+
+	return result
+}
@@ -2,21 +2,40 @@ package action
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/rand"
+	"net"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/appscode/go-hetzner"
-	"github.com/go-kit/kit/log"
-	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
+	// ErrMaxTargetsExceeded defines the error if there are too many targets.
+	ErrMaxTargetsExceeded = errors.New("maximum number of targets exceeded")
+
+	// ErrRefreshFailed defines the error if no project could be refreshed.
+	ErrRefreshFailed = errors.New("failed to refresh any project")
+
+	// ErrMissingCredentials defines the error if no credentials are left.
+	ErrMissingCredentials = errors.New("missing any credentials")
+
+	// defaultPrefix defines the default prefix of the labels after the meta prefix.
+	defaultPrefix = "hetzner_"
+
 	// providerPrefix defines the general prefix for all labels.
-	providerPrefix = model.MetaLabelPrefix + "hetzner_"
+	providerPrefix = model.MetaLabelPrefix + defaultPrefix
 
 	// Labels defines all available labels for this provider.
 	Labels = map[string]string{
@@ -24,11 +43,16 @@ var (
 		"dc":        providerPrefix + "dc",
 		"flatrate":  providerPrefix + "flatrate",
 		"ip":        providerPrefix + "ipv4",
+		"location":  providerPrefix + "location",
+		"mapping":   providerPrefix + "mapping",
 		"name":      providerPrefix + "name",
 		"number":    providerPrefix + "number",
 		"product":   providerPrefix + "product",
 		"project":   providerPrefix + "project",
+		"projects":  providerPrefix + "projects",
+		"stale":     providerPrefix + "stale",
 		"status":    providerPrefix + "status",
+		"subnet":    providerPrefix + "subnet",
 		"throttled": providerPrefix + "throttled",
 		"traffic":   providerPrefix + "traffic",
 	}
@@ -36,74 +60,601 @@ var (
 
 // Discoverer implements the Prometheus discoverer interface.
 type Discoverer struct {
-	clients map[string]*hetzner.Client
-	logger  log.Logger
-	refresh int
-	lasts   map[string]struct{}
+	accounts map[string]*account
+	projects []string
+	logger   log.Logger
+	refresh  time.Duration
+	jitter   float64
+	stagger  time.Duration
+	random   *rand.Rand
+	subnets  bool
+	api      *apiClient
+	excludes []*net.IPNet
+	address  *addresser
+	ports    *portMapper
+	blackbox *blackbox
+	filter   *filter
+	relabel  *relabeler
+	keep     *patterns
+	statics  []*targetgroup.Group
+	prefix   string
+	max      int
+	stale    time.Duration
+	workers  int
+	timeout  time.Duration
+	lasts    map[string]int
+	labels   map[string]model.LabelSet
+	audit    *auditLog
+	cache    map[string]*fetched
+	snapshot string
+	reload   chan struct{}
+	ready    *readiness
+	status   *status
+	mutex    sync.RWMutex
 }
 
-// Run initializes fetching the targets for service discovery.
-func (d Discoverer) Run(ctx context.Context, ch chan<- []*targetgroup.Group) {
-	ticker := time.NewTicker(time.Duration(d.refresh) * time.Second)
+// Reload replaces the projects and credentials of the discoverer and triggers
+// a refresh of the targets.
+func (d *Discoverer) Reload(credentials []config.Credential) error {
+	accounts, projects, err := newAccounts(credentials, d.api)
 
-	for {
-		targets, err := d.getTargets(ctx)
+	if err != nil {
+		return err
+	}
+
+	names, err := projectPatterns(credentials)
+
+	if err != nil {
+		return err
+	}
 
-		if err == nil {
-			ch <- targets
+	d.mutex.Lock()
+	filter := *d.filter
+	filter.projects = names
+
+	for _, project := range d.projects {
+		if _, ok := accounts[project]; !ok {
+			refreshSuccess.DeleteLabelValues(project)
+			projectUp.DeleteLabelValues(project)
+			projectStale.DeleteLabelValues(project)
+			budgets.remove(project)
 		}
+	}
+
+	d.accounts = accounts
+	d.projects = projects
+	d.filter = &filter
+	d.mutex.Unlock()
+
+	select {
+	case d.reload <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+func newDiscoverer(cfg *config.Config, logger log.Logger) (*Discoverer, error) {
+	api, err := newAPIClient(cfg.Target)
+
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, projects, err := newAccounts(cfg.Target.Credentials, api)
+
+	if err != nil {
+		return nil, err
+	}
+
+	excludes := make([]*net.IPNet, 0, len(cfg.Target.Subnets.Exclude))
+
+	for _, exclude := range cfg.Target.Subnets.Exclude {
+		_, network, err := net.ParseCIDR(exclude)
+
+		if err != nil {
+			return nil, err
+		}
+
+		excludes = append(excludes, network)
+	}
+
+	addr, err := newAddresser(cfg.Target)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ports, err := newPortMapper(cfg.Target.Ports)
+
+	if err != nil {
+		return nil, err
+	}
+
+	bb, err := newBlackbox(cfg.Target.Blackbox)
+
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := newFilter(cfg.Target.Filters, cfg.Target.Credentials)
+
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := newRelabeler(cfg.Target.Relabel)
+
+	if err != nil {
+		return nil, err
+	}
+
+	keep, err := newPatterns(cfg.Target.Labels)
+
+	if err != nil {
+		return nil, err
+	}
+
+	statics, err := staticTargets(cfg.Target.Static)
+
+	if err != nil {
+		return nil, err
+	}
+
+	budgets.configure(cfg.Target.Budget.Requests, cfg.Target.Budget.Interval.Duration())
+
+	return &Discoverer{
+		accounts: accounts,
+		projects: projects,
+		logger:   logger,
+		refresh:  cfg.Target.Refresh.Duration(),
+		jitter:   cfg.Target.Jitter,
+		stagger:  cfg.Target.Stagger.Duration(),
+		random:   rand.New(rand.NewSource(time.Now().UnixNano())),
+		subnets:  cfg.Target.Subnets.Enabled,
+		api:      api,
+		excludes: excludes,
+		address:  addr,
+		ports:    ports,
+		blackbox: bb,
+		filter:   f,
+		relabel:  r,
+		keep:     keep,
+		statics:  statics,
+		prefix:   cfg.Target.Prefix,
+		max:      cfg.Target.MaxTargets,
+		stale:    cfg.Target.StaleAfter.Duration(),
+		workers:  cfg.Target.Workers,
+		timeout:  cfg.Target.Timeout.Duration(),
+		lasts:    make(map[string]int),
+		audit:    newAuditLog(cfg.Audit),
+		reload:   make(chan struct{}, 1),
+		status:   newStatus(),
+	}, nil
+}
+
+// Run initializes fetching the targets for service discovery. Every project
+// gets refreshed by its own interval, the targets of all other projects are
+// taken from the previous refresh. On startup the first refresh of every
+// project can be staggered and every interval can be extended by a jitter,
+// this way multiple instances sharing an account don't refresh in sync.
+func (d *Discoverer) Run(ctx context.Context, ch chan<- []*targetgroup.Group) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	next := make(map[string]time.Time)
+	first := true
+
+	for {
+		trigger := "interval"
 
 		select {
-		case <-ticker.C:
-			continue
+		case <-timer.C:
+		case <-d.reload:
+			trigger = "reload"
+			next = make(map[string]time.Time)
+
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
 		case <-ctx.Done():
 			return
 		}
+
+		d.mutex.RLock()
+		accounts, projects := d.accounts, d.projects
+		d.mutex.RUnlock()
+
+		now := time.Now()
+		due := make(map[string]struct{})
+
+		for _, project := range projects {
+			if at, ok := next[project]; !ok || !at.After(now) {
+				if !ok && trigger == "interval" && d.stagger > 0 {
+					next[project] = now.Add(d.staggered())
+					continue
+				}
+
+				if wait := budgets.wait(project); wait > 0 {
+					next[project] = now.Add(wait)
+					continue
+				}
+
+				due[project] = struct{}{}
+				next[project] = now.Add(d.jittered(d.interval(accounts[project])))
+			}
+		}
+
+		// Restored targets are passed immediately on startup, even if the
+		// first refresh of all projects has been staggered.
+		if len(due) > 0 || first && len(d.cache) > 0 {
+			targets, err := d.refreshTargets(withTrigger(ctx, trigger), due)
+
+			if err == nil {
+				ch <- targets
+			}
+		}
+
+		first = false
+
+		wait := d.refresh
+
+		for i, project := range projects {
+			if until := time.Until(next[project]); i == 0 || until < wait {
+				wait = until
+			}
+		}
+
+		timer.Reset(wait)
 	}
 }
 
-func (d *Discoverer) getTargets(ctx context.Context) ([]*targetgroup.Group, error) {
-	current := make(map[string]struct{})
-	targets := make([]*targetgroup.Group, 0)
+// interval returns the refresh interval of the account, which defaults to the
+// global refresh interval.
+func (d *Discoverer) interval(acc *account) time.Duration {
+	if acc != nil && acc.credential.Refresh > 0 {
+		return acc.credential.Refresh.Duration()
+	}
+
+	return d.refresh
+}
+
+// jittered extends the interval by a random share of up to the jitter.
+func (d *Discoverer) jittered(interval time.Duration) time.Duration {
+	if d.jitter <= 0 {
+		return interval
+	}
+
+	return interval + time.Duration(d.random.Float64()*d.jitter*float64(interval))
+}
+
+// staggered returns a random delay for the first refresh of a project.
+func (d *Discoverer) staggered() time.Duration {
+	return time.Duration(d.random.Int63n(int64(d.stagger)))
+}
+
+// fetched defines the servers and subnets of a project from the last refresh.
+type fetched struct {
+	account *account
+	servers []*hetzner.ServerSummary
+	subnets map[int][]subnet
+	at      time.Time
+}
+
+// fetchResult defines the outcome of fetching a single project.
+type fetchResult struct {
+	result *fetched
+	err    error
+}
+
+// fetchProjects fetches the projects concurrently by a pool of workers, the
+// results are collected per project.
+func (d *Discoverer) fetchProjects(ctx context.Context, projects []string, accounts map[string]*account) map[string]fetchResult {
+	results := make(map[string]fetchResult, len(projects))
+	queue := make(chan string)
+	workers := d.workers
+
+	if workers <= 0 || workers > len(projects) {
+		workers = len(projects)
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mutex sync.Mutex
+	)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for project := range queue {
+				result, err := d.fetchProject(ctx, project, accounts[project])
+
+				mutex.Lock()
+				results[project] = fetchResult{
+					result: result,
+					err:    err,
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	for _, project := range projects {
+		queue <- project
+	}
+
+	close(queue)
+	wg.Wait()
+
+	return results
+}
+
+// fetchProject requests the servers and subnets of a single project, all
+// requests of the project are canceled once the timeout has been reached.
+func (d *Discoverer) fetchProject(ctx context.Context, project string, acc *account) (*fetched, error) {
+	ctx, span := tracer.Start(ctx, "fetch", trace.WithAttributes(
+		attribute.String("project", project),
+	))
+	defer span.End()
+
+	if d.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.timeout)
+		defer cancel()
+	}
+
+	logger := d.refreshLogger(ctx)
+
+	started := time.Now()
+	servers, err := acc.fetch(ctx)
+
+	if err != nil {
+		if next := d.rotated(project, acc); next != nil {
+			level.Info(logger).Log(
+				"msg", "Retrying with rotated credentials",
+				"project", project,
+				"err", err,
+			)
+
+			requestFailures.WithLabelValues(project).Inc()
+
+			acc = next
+			servers, err = acc.fetch(ctx)
+		}
+	}
 
-	for project, client := range d.clients {
+	if err != nil {
+		failSpan(span, err)
+		return nil, err
+	}
+
+	refreshPhaseDuration.WithLabelValues(project, "list").Observe(time.Since(started).Seconds())
+
+	level.Debug(logger).Log(
+		"msg", "Requested servers",
+		"project", project,
+		"count", len(servers),
+	)
+
+	subnets := make(map[int][]subnet)
+
+	if previous, ok := d.cache[project]; ok && d.subnets && !budgets.allows(project, d.interval(acc)) {
+		level.Debug(logger).Log(
+			"msg", "Deferred subnets, request budget exhausted",
+			"project", project,
+		)
+
+		requestsDeferred.WithLabelValues(project, "subnet").Inc()
+
+		return &fetched{
+			account: acc,
+			servers: servers,
+			subnets: previous.subnets,
+			at:      time.Now(),
+		}, nil
+	}
+
+	if d.subnets {
 		now := time.Now()
-		servers, _, err := client.Server.ListServers()
-		requestDuration.WithLabelValues(project).Observe(time.Since(now).Seconds())
+		subnets, err = acc.subnets(ctx)
+		refreshPhaseDuration.WithLabelValues(project, "details").Observe(time.Since(now).Seconds())
 
 		if err != nil {
-			level.Warn(d.logger).Log(
+			level.Warn(logger).Log(
+				"msg", "Failed to fetch subnets",
+				"project", project,
+				"err", err,
+			)
+
+			requestFailures.WithLabelValues(project).Inc()
+			subnets = make(map[int][]subnet)
+		}
+	}
+
+	return &fetched{
+		account: acc,
+		servers: servers,
+		subnets: subnets,
+		at:      time.Now(),
+	}, nil
+}
+
+func (d *Discoverer) getTargets(ctx context.Context) ([]*targetgroup.Group, error) {
+	return d.refreshTargets(ctx, nil)
+}
+
+// refreshTargets fetches the due projects, or all projects if due is nil, and
+// builds the targets together with the cached results of the other projects.
+func (d *Discoverer) refreshTargets(ctx context.Context, due map[string]struct{}) ([]*targetgroup.Group, error) {
+	defer func(started time.Time) {
+		refreshDuration.Observe(time.Since(started).Seconds())
+	}(time.Now())
+
+	ctx, span := tracer.Start(ctx, "refresh")
+	defer span.End()
+
+	ctx = withRefresh(ctx, span)
+	logger := d.refreshLogger(ctx)
+
+	d.mutex.RLock()
+	accounts, projects, filters := d.accounts, d.projects, d.filter
+	d.mutex.RUnlock()
+
+	cache := make(map[string]*fetched, len(projects))
+	pending := make([]string, 0, len(projects))
+	succeeded := 0
+
+	for _, project := range projects {
+		if _, ok := due[project]; due != nil && !ok {
+			if result, ok := d.cache[project]; ok {
+				cache[project] = result
+			}
+
+			continue
+		}
+
+		if wait := budgets.wait(project); wait > 0 {
+			level.Warn(logger).Log(
+				"msg", "Skipped fetching servers, rate limit exceeded",
+				"project", project,
+				"wait", wait.Round(time.Second),
+			)
+
+			requestsDeferred.WithLabelValues(project, "server").Inc()
+
+			if result, ok := d.cache[project]; ok {
+				cache[project] = result
+			}
+
+			continue
+		}
+
+		pending = append(pending, project)
+	}
+
+	results := d.fetchProjects(ctx, pending, accounts)
+
+	for _, project := range pending {
+		result, err := results[project].result, results[project].err
+
+		if err != nil {
+			level.Warn(logger).Log(
 				"msg", "Failed to fetch servers",
 				"project", project,
 				"err", err,
 			)
 
 			requestFailures.WithLabelValues(project).Inc()
+			d.status.fail(project, err)
+			projectUp.WithLabelValues(project).Set(0)
+
+			if result, ok := d.cache[project]; ok {
+				level.Info(logger).Log(
+					"msg", "Serving previous targets",
+					"project", project,
+					"refreshed", result.at.Format(time.RFC3339),
+				)
+
+				cache[project] = result
+			}
+
 			continue
 		}
 
-		level.Debug(d.logger).Log(
-			"msg", "Requested servers",
-			"project", project,
-			"count", len(servers),
+		cache[project] = result
+		succeeded++
+
+		d.status.succeed(project, len(result.servers))
+		refreshSuccess.WithLabelValues(project).SetToCurrentTime()
+		projectUp.WithLabelValues(project).Set(1)
+	}
+
+	if len(cache) == 0 && len(projects) > 0 {
+		level.Error(logger).Log(
+			"msg", "Refused to write targets, all projects failed",
 		)
 
-		for _, server := range servers {
+		failSpan(span, ErrRefreshFailed)
+		return nil, ErrRefreshFailed
+	}
+
+	d.cache = cache
+
+	current := make(map[string]struct{})
+	targets := make([]*targetgroup.Group, 0)
+	seen := make(map[int][]*targetgroup.Group)
+
+	for _, project := range projects {
+		result, ok := cache[project]
+
+		if !ok {
+			continue
+		}
+
+		rendered := time.Now()
+		_, render := tracer.Start(ctx, "render", trace.WithAttributes(
+			attribute.String("project", project),
+		))
+		acc, subnets := result.account, result.subnets
+		stale := d.stale > 0 && time.Since(result.at) > d.interval(acc)+d.stale
+
+		if stale {
+			projectStale.WithLabelValues(project).Set(1)
+		} else {
+			projectStale.WithLabelValues(project).Set(0)
+		}
+
+		for _, server := range result.servers {
+			if !filters.matches(project, server) {
+				level.Debug(logger).Log(
+					"msg", "Server filtered",
+					"project", project,
+					"number", server.ServerNumber,
+				)
+
+				continue
+			}
+
+			if groups, ok := seen[server.ServerNumber]; ok {
+				for _, group := range groups {
+					name := model.LabelName(Labels["projects"])
+					group.Labels[name] = group.Labels[name] + "," + model.LabelValue(project)
+				}
+
+				level.Debug(logger).Log(
+					"msg", "Server deduplicated",
+					"project", project,
+					"source", fmt.Sprintf("hetzner/%d", server.ServerNumber),
+				)
+
+				continue
+			}
+
+			addr := normalizeAddress(server.ServerIP)
+
 			target := &targetgroup.Group{
 				Source: fmt.Sprintf("hetzner/%d", server.ServerNumber),
 				Targets: []model.LabelSet{
 					{
-						model.AddressLabel: model.LabelValue(server.ServerIP),
+						model.AddressLabel: model.LabelValue(addr),
 					},
 				},
 				Labels: model.LabelSet{
-					model.AddressLabel:                   model.LabelValue(server.ServerIP),
+					model.AddressLabel:                   model.LabelValue(addr),
 					model.LabelName(Labels["project"]):   model.LabelValue(project),
+					model.LabelName(Labels["projects"]):  model.LabelValue(project),
 					model.LabelName(Labels["name"]):      model.LabelValue(server.ServerName),
 					model.LabelName(Labels["number"]):    model.LabelValue(strconv.Itoa(int(server.ServerNumber))),
 					model.LabelName(Labels["ip"]):        model.LabelValue(server.ServerIP),
 					model.LabelName(Labels["product"]):   model.LabelValue(server.Product),
 					model.LabelName(Labels["dc"]):        model.LabelValue(strings.ToLower(server.Dc)),
+					model.LabelName(Labels["location"]):  model.LabelValue(location(server.Dc)),
 					model.LabelName(Labels["traffic"]):   model.LabelValue(server.Traffic),
 					model.LabelName(Labels["flatrate"]):  model.LabelValue(strconv.FormatBool(server.Flatrate)),
 					model.LabelName(Labels["status"]):    model.LabelValue(server.Status),
@@ -112,7 +663,15 @@ func (d *Discoverer) getTargets(ctx context.Context) ([]*targetgroup.Group, erro
 				},
 			}
 
-			level.Debug(d.logger).Log(
+			if d.stale > 0 {
+				target.Labels[model.LabelName(Labels["stale"])] = model.LabelValue(strconv.FormatBool(stale))
+			}
+
+			for key, value := range acc.labels {
+				target.Labels[key] = value
+			}
+
+			level.Debug(logger).Log(
 				"msg", "Server added",
 				"project", project,
 				"source", target.Source,
@@ -120,17 +679,156 @@ func (d *Discoverer) getTargets(ctx context.Context) ([]*targetgroup.Group, erro
 
 			current[target.Source] = struct{}{}
 			targets = append(targets, target)
+			seen[server.ServerNumber] = append(seen[server.ServerNumber], target)
+
+			for _, block := range subnets[server.ServerNumber] {
+				for _, addr := range block.addresses(d.excludes) {
+					labels := target.Labels.Clone()
+					labels[model.AddressLabel] = model.LabelValue(addr)
+					labels[model.LabelName(Labels["subnet"])] = model.LabelValue(block.String())
+
+					sub := &targetgroup.Group{
+						Source: fmt.Sprintf("hetzner/%d/%s", server.ServerNumber, addr),
+						Targets: []model.LabelSet{
+							{
+								model.AddressLabel: model.LabelValue(addr),
+							},
+						},
+						Labels: labels,
+					}
+
+					level.Debug(logger).Log(
+						"msg", "Subnet address added",
+						"project", project,
+						"source", sub.Source,
+					)
+
+					current[sub.Source] = struct{}{}
+					targets = append(targets, sub)
+					seen[server.ServerNumber] = append(seen[server.ServerNumber], sub)
+				}
+			}
 		}
 
+		refreshPhaseDuration.WithLabelValues(project, "render").Observe(time.Since(rendered).Seconds())
+		render.End()
 	}
 
-	for k := range d.lasts {
+	if d.address != nil {
+		for _, target := range targets {
+			if err := d.address.apply(target); err != nil {
+				level.Warn(logger).Log(
+					"msg", "Failed to render target address",
+					"source", target.Source,
+					"err", err,
+				)
+			}
+		}
+	}
+
+	if d.ports != nil {
+		targets = d.ports.apply(targets, current)
+	}
+
+	if d.blackbox != nil {
+		for _, target := range targets {
+			if err := d.blackbox.apply(target); err != nil {
+				level.Warn(logger).Log(
+					"msg", "Failed to render blackbox target",
+					"source", target.Source,
+					"err", err,
+				)
+			}
+		}
+	}
+
+	if d.prefix != defaultPrefix {
+		for _, target := range targets {
+			prefixGroup(d.prefix, target)
+		}
+	}
+
+	if d.relabel != nil {
+		result := make([]*targetgroup.Group, 0, len(targets))
+
+		for _, target := range targets {
+			if !d.relabel.apply(target) {
+				level.Debug(logger).Log(
+					"msg", "Server dropped by relabeling",
+					"source", target.Source,
+				)
+
+				delete(current, target.Source)
+				continue
+			}
+
+			result = append(result, target)
+		}
+
+		targets = result
+	}
+
+	counts := d.targetCounts(targets)
+
+	if len(d.keep.include) > 0 || len(d.keep.exclude) > 0 {
+		for _, target := range targets {
+			for key := range target.Labels {
+				if key != model.AddressLabel && !d.keep.matches(string(key)) {
+					delete(target.Labels, key)
+				}
+			}
+		}
+	}
+
+	for _, static := range d.statics {
+		current[static.Source] = struct{}{}
+		targets = append(targets, static)
+	}
+
+	if count := countTargets(targets); d.max > 0 && count > d.max {
+		level.Error(logger).Log(
+			"msg", "Refused to write targets, maximum exceeded",
+			"count", count,
+			"max", d.max,
+		)
+
+		maxTargetsExceeded.Inc()
+		failSpan(span, ErrMaxTargetsExceeded)
+
+		return nil, ErrMaxTargetsExceeded
+	}
+
+	targetsGauge.Reset()
+
+	for key, count := range counts {
+		targetsGauge.WithLabelValues(key.project, key.dc).Set(float64(count))
+	}
+
+	sizes := make(map[string]int, len(current))
+
+	for _, target := range targets {
+		sizes[target.Source] += len(target.Targets)
+	}
+
+	// The counters track the number of targets, a group of an existing source
+	// changing its number of targets counts the difference.
+	for k := range current {
+		if diff := sizes[k] - d.lasts[k]; diff > 0 {
+			targetsAdded.Add(float64(diff))
+		} else if diff < 0 {
+			targetsRemoved.Add(float64(-diff))
+		}
+	}
+
+	for k, size := range d.lasts {
 		if _, ok := current[k]; !ok {
-			level.Debug(d.logger).Log(
+			level.Debug(logger).Log(
 				"msg", "Server deleted",
 				"source", k,
 			)
 
+			targetsRemoved.Add(float64(size))
+
 			targets = append(
 				targets,
 				&targetgroup.Group{
@@ -140,6 +838,76 @@ func (d *Discoverer) getTargets(ctx context.Context) ([]*targetgroup.Group, erro
 		}
 	}
 
-	d.lasts = current
+	if succeeded > 0 {
+		d.ready.refresh()
+	}
+
+	d.status.update(countTargets(targets))
+	d.logChanges(ctx, targets)
+
+	// The state gets persisted after the changes have been detected, this way
+	// it contains the labels of the written targets for the next start.
+	if succeeded > 0 {
+		if err := d.persist(); err != nil {
+			level.Warn(logger).Log(
+				"msg", "Failed to persist state",
+				"file", d.snapshot,
+				"err", err,
+			)
+		}
+	}
+
+	span.SetAttributes(
+		attribute.Int("projects", succeeded),
+		attribute.Int("targets", countTargets(targets)),
+	)
+
+	d.lasts = sizes
 	return targets, nil
 }
+
+// targetKey defines the labels of the target gauge.
+type targetKey struct {
+	project string
+	dc      string
+}
+
+// targetCounts sums up the targets per project and datacenter, the labels are
+// taken before they could be dropped by the label filters.
+func (d *Discoverer) targetCounts(targets []*targetgroup.Group) map[targetKey]int {
+	project := model.LabelName(d.label("project"))
+	dc := model.LabelName(d.label("dc"))
+	result := make(map[targetKey]int)
+
+	for _, target := range targets {
+		key := targetKey{
+			project: string(target.Labels[project]),
+			dc:      string(target.Labels[dc]),
+		}
+
+		result[key] += len(target.Targets)
+	}
+
+	return result
+}
+
+// countTargets sums up the targets of all groups.
+func countTargets(targets []*targetgroup.Group) int {
+	result := 0
+
+	for _, target := range targets {
+		result += len(target.Targets)
+	}
+
+	return result
+}
+
+// label returns the name of a label with the configured prefix.
+func (d *Discoverer) label(key string) string {
+	return prefixLabel(d.prefix, Labels[key])
+}
+
+// location extracts the location like fsn1 from datacenters like fsn1-dc14.
+func location(dc string) string {
+	return strings.SplitN(strings.ToLower(dc), "-", 2)[0]
+}
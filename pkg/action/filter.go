@@ -0,0 +1,239 @@
+package action
+
+import (
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/appscode/go-hetzner"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
+)
+
+// filter decides which servers are part of the discovered targets.
+type filter struct {
+	datacenters map[string]struct{}
+	products    *patterns
+	names       *patterns
+	projects    map[string]*patterns
+	allow       map[int]struct{}
+	deny        map[int]struct{}
+	statuses    map[string]struct{}
+	cancelled   config.Cancelled
+}
+
+// matches checks if the server of a project passes all defined filters.
+func (f *filter) matches(project string, server *hetzner.ServerSummary) bool {
+	if _, ok := f.deny[server.ServerNumber]; ok {
+		return false
+	}
+
+	if _, ok := f.allow[server.ServerNumber]; len(f.allow) > 0 && !ok {
+		return false
+	}
+
+	if len(f.datacenters) > 0 {
+		_, dc := f.datacenters[strings.ToLower(server.Dc)]
+		_, loc := f.datacenters[location(server.Dc)]
+
+		if !dc && !loc {
+			return false
+		}
+	}
+
+	if _, ok := f.statuses[strings.ToLower(server.Status)]; len(f.statuses) > 0 && !ok {
+		return false
+	}
+
+	if server.Cancelled && f.cancelled.Exclude && !f.graceful(server) {
+		return false
+	}
+
+	if !f.products.matches(server.Product) {
+		return false
+	}
+
+	if !f.names.matches(server.ServerName) {
+		return false
+	}
+
+	if names, ok := f.projects[project]; ok && !names.matches(server.ServerName) {
+		return false
+	}
+
+	return true
+}
+
+// graceful checks if a cancelled server is still within the grace period, the
+// paid until date is used as cancellation date and the server is kept until
+// the defined number of days before it.
+func (f *filter) graceful(server *hetzner.ServerSummary) bool {
+	if f.cancelled.Grace <= 0 {
+		return false
+	}
+
+	until, err := time.Parse("2006-01-02", server.PaidUntil)
+
+	if err != nil {
+		return false
+	}
+
+	return time.Now().Before(until.AddDate(0, 0, -f.cancelled.Grace))
+}
+
+func newFilter(cfg config.Filters, credentials []config.Credential) (*filter, error) {
+	f := &filter{
+		datacenters: make(map[string]struct{}, len(cfg.Datacenters)),
+		allow:       make(map[int]struct{}, len(cfg.Numbers.Allow)),
+		deny:        make(map[int]struct{}, len(cfg.Numbers.Deny)),
+		statuses:    make(map[string]struct{}, len(cfg.Statuses)),
+		cancelled:   cfg.Cancelled,
+	}
+
+	for _, status := range cfg.Statuses {
+		f.statuses[strings.ToLower(status)] = struct{}{}
+	}
+
+	for _, number := range cfg.Numbers.Allow {
+		f.allow[number] = struct{}{}
+	}
+
+	for _, number := range cfg.Numbers.Deny {
+		f.deny[number] = struct{}{}
+	}
+
+	for _, dc := range cfg.Datacenters {
+		f.datacenters[strings.ToLower(dc)] = struct{}{}
+	}
+
+	products, err := newPatterns(cfg.Products)
+
+	if err != nil {
+		return nil, err
+	}
+
+	f.products = products
+
+	names, err := newPatterns(cfg.Names)
+
+	if err != nil {
+		return nil, err
+	}
+
+	f.names = names
+
+	projects, err := projectPatterns(credentials)
+
+	if err != nil {
+		return nil, err
+	}
+
+	f.projects = projects
+
+	return f, nil
+}
+
+// projectPatterns builds the name patterns defined per project.
+func projectPatterns(credentials []config.Credential) (map[string]*patterns, error) {
+	result := make(map[string]*patterns, len(credentials))
+
+	for _, credential := range credentials {
+		names, err := newPatterns(credential.Names)
+
+		if err != nil {
+			return nil, err
+		}
+
+		result[credential.Project] = names
+	}
+
+	return result, nil
+}
+
+// pattern matches values by a glob or by a regular expression wrapped in
+// slashes like /^EX\d+$/.
+type pattern struct {
+	glob   string
+	regexp *regexp.Regexp
+}
+
+func (p pattern) matches(value string) bool {
+	if p.regexp != nil {
+		return p.regexp.MatchString(value)
+	}
+
+	ok, _ := path.Match(p.glob, value)
+	return ok
+}
+
+func newPattern(value string) (pattern, error) {
+	if len(value) > 1 && strings.HasPrefix(value, "/") && strings.HasSuffix(value, "/") {
+		re, err := regexp.Compile(value[1 : len(value)-1])
+
+		if err != nil {
+			return pattern{}, err
+		}
+
+		return pattern{regexp: re}, nil
+	}
+
+	if _, err := path.Match(value, ""); err != nil {
+		return pattern{}, err
+	}
+
+	return pattern{glob: value}, nil
+}
+
+// patterns combines include and exclude patterns, a value matches if it
+// matches any include pattern and none of the exclude patterns.
+type patterns struct {
+	include []pattern
+	exclude []pattern
+}
+
+func (p *patterns) matches(value string) bool {
+	if len(p.include) > 0 && !matchAny(p.include, value) {
+		return false
+	}
+
+	return !matchAny(p.exclude, value)
+}
+
+func newPatterns(cfg config.Patterns) (*patterns, error) {
+	p := &patterns{
+		include: make([]pattern, 0, len(cfg.Include)),
+		exclude: make([]pattern, 0, len(cfg.Exclude)),
+	}
+
+	for _, value := range cfg.Include {
+		result, err := newPattern(value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		p.include = append(p.include, result)
+	}
+
+	for _, value := range cfg.Exclude {
+		result, err := newPattern(value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		p.exclude = append(p.exclude, result)
+	}
+
+	return p, nil
+}
+
+func matchAny(patterns []pattern, value string) bool {
+	for _, p := range patterns {
+		if p.matches(value) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,129 @@
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/common/model"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
+)
+
+var (
+	// inventoryGroupRegexp defines the chars replaced within group names.
+	inventoryGroupRegexp = regexp.MustCompile(`[^a-z0-9_]+`)
+)
+
+type inventoryGroup struct {
+	Hosts    []string `json:"hosts,omitempty"`
+	Children []string `json:"children,omitempty"`
+}
+
+// Inventory handles the inventory sub-command.
+func Inventory(cfg *config.Config, logger log.Logger, host string, w io.Writer) error {
+	disc, err := newDiscoverer(cfg, logger)
+
+	if err != nil {
+		return err
+	}
+
+	targets, err := disc.getTargets(context.Background())
+
+	if err != nil {
+		return err
+	}
+
+	hostvars := make(map[string]map[string]string)
+	groups := make(map[string]*inventoryGroup)
+
+	for _, target := range targets {
+		if len(target.Targets) == 0 {
+			continue
+		}
+
+		if _, ok := target.Labels[model.LabelName(disc.label("subnet"))]; ok {
+			continue
+		}
+
+		if strings.HasPrefix(target.Source, "static/") {
+			continue
+		}
+
+		addr := string(target.Labels[model.AddressLabel])
+		name := string(target.Labels[model.LabelName(disc.label("name"))])
+
+		if name == "" {
+			name = addr
+		}
+
+		vars := map[string]string{
+			"ansible_host": addr,
+		}
+
+		for key, value := range target.Labels {
+			if !strings.HasPrefix(string(key), model.MetaLabelPrefix) {
+				continue
+			}
+
+			vars[strings.TrimPrefix(string(key), model.MetaLabelPrefix)] = string(value)
+		}
+
+		hostvars[name] = vars
+
+		for _, label := range []string{"dc", "product", "project"} {
+			value := string(target.Labels[model.LabelName(disc.label(label))])
+
+			if value == "" {
+				continue
+			}
+
+			group := inventoryGroupRegexp.ReplaceAllString(
+				strings.ToLower(label+"_"+value),
+				"_",
+			)
+
+			if _, ok := groups[group]; !ok {
+				groups[group] = &inventoryGroup{}
+			}
+
+			groups[group].Hosts = append(groups[group].Hosts, name)
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if host != "" {
+		if vars, ok := hostvars[host]; ok {
+			return encoder.Encode(vars)
+		}
+
+		return encoder.Encode(map[string]string{})
+	}
+
+	result := map[string]interface{}{
+		"_meta": map[string]interface{}{
+			"hostvars": hostvars,
+		},
+	}
+
+	children := make([]string, 0, len(groups))
+
+	for name, group := range groups {
+		sort.Strings(group.Hosts)
+		children = append(children, name)
+		result[name] = group
+	}
+
+	sort.Strings(children)
+
+	result["all"] = &inventoryGroup{
+		Children: children,
+	}
+
+	return encoder.Encode(result)
+}
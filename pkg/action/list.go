@@ -0,0 +1,163 @@
+package action
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
+)
+
+var (
+	// ErrListFormatInvalid defines the error if the list format is unsupported.
+	ErrListFormatInvalid = errors.New("list format is not supported")
+
+	// listColumns defines the label columns printed by the table format.
+	listColumns = []string{"number", "name", "project", "ip", "dc", "product", "status", "subnet"}
+)
+
+type listEntry struct {
+	Source string            `json:"source"`
+	Labels map[string]string `json:"labels"`
+}
+
+// List handles the list sub-command.
+func List(cfg *config.Config, logger log.Logger, format string, w io.Writer) error {
+	disc, err := newDiscoverer(cfg, logger)
+
+	if err != nil {
+		return err
+	}
+
+	targets, err := disc.getTargets(context.Background())
+
+	if err != nil {
+		return err
+	}
+
+	entries := listEntries(targets)
+
+	switch strings.ToLower(format) {
+	case "table":
+		return listTable(disc, entries, w)
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(entries)
+	case "csv":
+		return listCSV(entries, w)
+	}
+
+	return ErrListFormatInvalid
+}
+
+func listEntries(targets []*targetgroup.Group) []listEntry {
+	result := make([]listEntry, 0, len(targets))
+
+	for _, target := range targets {
+		if len(target.Targets) == 0 {
+			continue
+		}
+
+		labels := make(map[string]string, len(target.Labels))
+
+		for key, value := range target.Labels {
+			labels[string(key)] = string(value)
+		}
+
+		result = append(result, listEntry{
+			Source: target.Source,
+			Labels: labels,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Source < result[j].Source
+	})
+
+	return result
+}
+
+func listTable(disc *Discoverer, entries []listEntry, w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := make([]string, 0, len(listColumns)+1)
+
+	for _, column := range listColumns {
+		header = append(header, strings.ToUpper(column))
+	}
+
+	header = append(header, "ADDRESS")
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+
+	for _, entry := range entries {
+		row := make([]string, 0, len(header))
+
+		for _, column := range listColumns {
+			row = append(row, listValue(entry.Labels[disc.label(column)]))
+		}
+
+		row = append(row, listValue(entry.Labels[model.AddressLabel]))
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+func listCSV(entries []listEntry, w io.Writer) error {
+	keys := make(map[string]struct{})
+
+	for _, entry := range entries {
+		for key := range entry.Labels {
+			keys[key] = struct{}{}
+		}
+	}
+
+	header := make([]string, 0, len(keys)+1)
+
+	for key := range keys {
+		header = append(header, key)
+	}
+
+	sort.Strings(header)
+	header = append([]string{"source"}, header...)
+
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		row := make([]string, 0, len(header))
+		row = append(row, entry.Source)
+
+		for _, key := range header[1:] {
+			row = append(row, entry.Labels[key])
+		}
+
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func listValue(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
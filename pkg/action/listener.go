@@ -0,0 +1,120 @@
+package action
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// unixPrefix defines the prefix of addresses for unix domain sockets.
+	unixPrefix = "unix://"
+
+	// systemdFirstFD defines the first file descriptor passed by systemd.
+	systemdFirstFD = 3
+)
+
+var (
+	// ErrMissingSystemdSocket defines the error if no socket has been passed.
+	ErrMissingSystemdSocket = errors.New("no socket passed by systemd")
+)
+
+// listener creates the listener for the web server, IPv6 literals get bound
+// as IPv6-only to properly support IPv6-first hosts. Addresses with the unix
+// prefix are bound as unix domain sockets, stale sockets get removed.
+func listener(addr string) (net.Listener, error) {
+	if strings.HasPrefix(addr, unixPrefix) {
+		path := strings.TrimPrefix(addr, unixPrefix)
+
+		if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+			if err := os.Remove(path); err != nil {
+				return nil, err
+			}
+		}
+
+		return net.Listen("unix", path)
+	}
+
+	network := "tcp"
+
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		if ip := net.ParseIP(stripZone(host)); ip != nil && ip.To4() == nil {
+			network = "tcp6"
+		}
+	}
+
+	return net.Listen(network, addr)
+}
+
+// systemdListener uses the first socket passed by the systemd socket
+// activation, the environment variables get removed afterwards.
+func systemdListener() (net.Listener, error) {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return nil, ErrMissingSystemdSocket
+	}
+
+	if fds, err := strconv.Atoi(os.Getenv("LISTEN_FDS")); err != nil || fds < 1 {
+		return nil, ErrMissingSystemdSocket
+	}
+
+	file := os.NewFile(uintptr(systemdFirstFD), "systemd")
+	defer file.Close()
+
+	return net.FileListener(file)
+}
+
+// normalizeAddress converts addresses into the canonical representation and
+// properly brackets IPv6 addresses if they are combined with a port.
+func normalizeAddress(addr string) string {
+	if host, port, err := net.SplitHostPort(addr); err == nil {
+		if ip := net.ParseIP(stripZone(host)); ip != nil {
+			return net.JoinHostPort(canonicalHost(host, ip), port)
+		}
+
+		return addr
+	}
+
+	if ip := net.ParseIP(stripZone(addr)); ip != nil {
+		return canonicalHost(addr, ip)
+	}
+
+	return addr
+}
+
+func canonicalHost(host string, ip net.IP) string {
+	if i := strings.IndexByte(host, '%'); i >= 0 {
+		return ip.String() + host[i:]
+	}
+
+	return ip.String()
+}
+
+func stripZone(host string) string {
+	if i := strings.IndexByte(host, '%'); i >= 0 {
+		return host[:i]
+	}
+
+	return host
+}
+
+// allowedNetworks parses the allowed networks of the web server, the CIDRs
+// have already been validated by the command.
+func allowedNetworks(cidrs []string) []*net.IPNet {
+	result := make([]*net.IPNet, 0, len(cidrs))
+
+	for _, cidr := range cidrs {
+		if _, network, err := net.ParseCIDR(cidr); err == nil {
+			result = append(result, network)
+		}
+	}
+
+	return result
+}
@@ -1,13 +1,24 @@
 package action
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"time"
 
-	"github.com/go-kit/kit/log"
-	"github.com/go-kit/kit/log/level"
+	"github.com/appscode/go-hetzner"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/promhippie/prometheus-hetzner-sd/pkg/version"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/writer"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
@@ -34,6 +45,201 @@ var (
 		},
 		[]string{"project"},
 	)
+
+	requestRetries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "request_retries_total",
+			Help:      "Total number of retried requests to the Hetzner API.",
+		},
+		[]string{"project"},
+	)
+
+	requestsDeferred = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_deferred_total",
+			Help:      "Total number of requests to the Hetzner API deferred because of the rate limit.",
+		},
+		[]string{"project", "endpoint"},
+	)
+
+	requestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "Total number of requests to the Hetzner API by endpoint and status code.",
+		},
+		[]string{"project", "endpoint", "status"},
+	)
+
+	refreshSuccess = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "last_refresh_success_timestamp_seconds",
+			Help:      "Timestamp of the last successful refresh of a project.",
+		},
+		[]string{"project"},
+	)
+
+	projectUp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "project_up",
+			Help:      "Whether the last refresh of a project succeeded.",
+		},
+		[]string{"project"},
+	)
+
+	projectStale = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "project_stale",
+			Help:      "Whether the targets of a project are served from a refresh older than the grace period.",
+		},
+		[]string{"project"},
+	)
+
+	refreshDuration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "refresh_duration_seconds",
+			Help:      "Histogram of durations for the full refresh of the targets.",
+			Buckets:   []float64{0.1, 0.5, 1.0, 2.0, 5.0, 10.0, 30.0, 60.0, 120.0},
+		},
+	)
+
+	refreshPhaseDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "refresh_phase_duration_seconds",
+			Help:      "Histogram of durations for the phases of a refresh like list, details or render.",
+			Buckets:   []float64{0.001, 0.01, 0.1, 0.5, 1.0, 2.0, 5.0, 10.0, 30.0},
+		},
+		[]string{"project", "phase"},
+	)
+
+	writeDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "write_duration_seconds",
+			Help:      "Histogram of durations for writes of the outputs.",
+			Buckets:   []float64{0.001, 0.01, 0.1, 0.5, 1.0, 2.0, 5.0, 10.0},
+		},
+		[]string{"output"},
+	)
+
+	writeAttempts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "write_attempts_total",
+			Help:      "Total number of attempted writes of the outputs.",
+		},
+		[]string{"output"},
+	)
+
+	writeFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "write_failures_total",
+			Help:      "Total number of failed writes of the outputs.",
+		},
+		[]string{"output"},
+	)
+
+	writeBytes = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "write_bytes_total",
+			Help:      "Total number of bytes of the JSON encoded groups written to the outputs.",
+		},
+		[]string{"output"},
+	)
+
+	writeSkipped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "write_skipped_total",
+			Help:      "Total number of skipped writes of the outputs because of unchanged content.",
+		},
+		[]string{"output"},
+	)
+
+	writeTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "last_write_timestamp_seconds",
+			Help:      "Timestamp of the last successful or skipped write of the outputs.",
+		},
+		[]string{"output"},
+	)
+
+	writeHash = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "write_hash",
+			Help:      "FNV-1a hash of the JSON encoded groups of the last write.",
+		},
+		[]string{"output"},
+	)
+
+	writeGeneration = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "write_generation",
+			Help:      "Generation of the written groups, increased whenever the content changes.",
+		},
+		[]string{"output"},
+	)
+
+	targetsGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "targets",
+			Help:      "Number of discovered targets per project and datacenter.",
+		},
+		[]string{"project", "dc"},
+	)
+
+	targetsAdded = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "targets_added_total",
+			Help:      "Total number of targets added by refreshes.",
+		},
+	)
+
+	targetsRemoved = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "targets_removed_total",
+			Help:      "Total number of targets removed by refreshes.",
+		},
+	)
+
+	configReloads = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "config_reloads_total",
+			Help:      "Total number of successful reloads of the configuration file.",
+		},
+	)
+
+	configReloadFailures = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "config_reload_failures_total",
+			Help:      "Total number of failed reloads of the configuration file.",
+		},
+	)
+
+	maxTargetsExceeded = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "max_targets_exceeded_total",
+			Help:      "Total number of refreshes refused because of too many targets.",
+		},
+	)
 )
 
 func init() {
@@ -46,6 +252,29 @@ func init() {
 
 	registry.MustRegister(requestDuration)
 	registry.MustRegister(requestFailures)
+	registry.MustRegister(requestRetries)
+	registry.MustRegister(requestsTotal)
+	registry.MustRegister(requestsDeferred)
+	registry.MustRegister(budgets)
+	registry.MustRegister(refreshSuccess)
+	registry.MustRegister(projectUp)
+	registry.MustRegister(projectStale)
+	registry.MustRegister(refreshDuration)
+	registry.MustRegister(refreshPhaseDuration)
+	registry.MustRegister(writeDuration)
+	registry.MustRegister(writeAttempts)
+	registry.MustRegister(writeSkipped)
+	registry.MustRegister(writeFailures)
+	registry.MustRegister(writeBytes)
+	registry.MustRegister(writeTimestamp)
+	registry.MustRegister(writeHash)
+	registry.MustRegister(writeGeneration)
+	registry.MustRegister(targetsGauge)
+	registry.MustRegister(targetsAdded)
+	registry.MustRegister(targetsRemoved)
+	registry.MustRegister(configReloads)
+	registry.MustRegister(configReloadFailures)
+	registry.MustRegister(maxTargetsExceeded)
 }
 
 type promLogger struct {
@@ -57,3 +286,104 @@ func (pl promLogger) Println(v ...interface{}) {
 		"msg", fmt.Sprintln(v...),
 	)
 }
+
+// observed wraps the writer to record the outcome of the writes, the name is
+// based on the engine with an index for multiple outputs of the same engine.
+func observed(w writer.Writer, engine string, names map[string]int) writer.Writer {
+	name := engine
+
+	if count := names[engine]; count > 0 {
+		name = fmt.Sprintf("%s-%d", engine, count)
+	}
+
+	names[engine]++
+
+	return &observedWriter{
+		Writer: w,
+		name:   name,
+	}
+}
+
+// observedWriter records the durations and outcomes of the writes of an
+// output. The size and hash are taken from the JSON encoding of the groups,
+// independent of the format the output engine is writing. Writes of content
+// equal to the previous write are skipped.
+type observedWriter struct {
+	writer.Writer
+
+	name       string
+	previous   []byte
+	hash       uint32
+	generation int
+}
+
+// Write implements the writer interface.
+func (w *observedWriter) Write(groups []writer.Group) error {
+	defer func(started time.Time) {
+		writeDuration.WithLabelValues(w.name).Observe(time.Since(started).Seconds())
+	}(time.Now())
+
+	_, span := tracer.Start(context.Background(), "write", trace.WithAttributes(
+		attribute.String("output", w.name),
+	))
+	defer span.End()
+
+	content, err := json.Marshal(groups)
+
+	if err != nil {
+		content = nil
+	}
+
+	if content != nil && w.generation > 0 && bytes.Equal(content, w.previous) {
+		span.SetAttributes(attribute.Bool("skipped", true))
+
+		writeSkipped.WithLabelValues(w.name).Inc()
+		writeTimestamp.WithLabelValues(w.name).SetToCurrentTime()
+
+		return nil
+	}
+
+	writeAttempts.WithLabelValues(w.name).Inc()
+
+	if err := w.Writer.Write(groups); err != nil {
+		writeFailures.WithLabelValues(w.name).Inc()
+		failSpan(span, err)
+
+		return err
+	}
+
+	if content == nil {
+		return nil
+	}
+
+	h := fnv.New32a()
+	h.Write(content)
+
+	w.previous = content
+	w.hash = h.Sum32()
+	w.generation++
+
+	writeBytes.WithLabelValues(w.name).Add(float64(len(content)))
+	writeTimestamp.WithLabelValues(w.name).SetToCurrentTime()
+	writeHash.WithLabelValues(w.name).Set(float64(w.hash))
+	writeGeneration.WithLabelValues(w.name).Set(float64(w.generation))
+
+	return nil
+}
+
+// observeRequest counts a request to the Hetzner API by the status code of the
+// response, requests failing without any response are counted as error.
+func observeRequest(project, endpoint string, resp *http.Response, err error) {
+	code := "error"
+
+	if resp != nil {
+		code = strconv.Itoa(resp.StatusCode)
+	} else if apiErr, ok := err.(*hetzner.APIError); ok && apiErr.Status > 0 {
+		code = strconv.Itoa(apiErr.Status)
+	} else if err == nil {
+		code = strconv.Itoa(http.StatusOK)
+	}
+
+	requestsTotal.WithLabelValues(project, endpoint, code).Inc()
+	budgets.observe(project, err)
+}
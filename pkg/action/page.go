@@ -0,0 +1,99 @@
+package action
+
+import (
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/version"
+)
+
+var statusTemplate = template.Must(template.New("status").Funcs(template.FuncMap{
+	"since": func(t time.Time) string {
+		if t.IsZero() {
+			return "never"
+		}
+
+		return time.Since(t).Truncate(time.Second).String() + " ago"
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Prometheus Hetzner SD</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.8em; text-align: left; }
+.error { color: #c00; }
+</style>
+</head>
+<body>
+<h1>Prometheus Hetzner SD</h1>
+<p>
+Version {{ .Version }} ({{ .Revision }}), built with {{ .Go }}<br>
+Engine {{ .Engine }}, {{ if .Ready }}ready{{ else }}not ready yet{{ end }}<br>
+{{ .Targets }} targets, last refresh {{ since .Refreshed }}
+</p>
+<p>
+<a href="{{ .Metrics }}">Metrics</a>{{ if .Discovery }} &middot; <a href="/sd">Service discovery</a>{{ end }}
+</p>
+<h2>Projects</h2>
+<table>
+<tr><th>Project</th><th>Last refresh</th><th>Servers</th><th>Last error</th></tr>
+{{ range .Projects }}<tr>
+<td>{{ .Project }}</td>
+<td>{{ since .Refreshed }}</td>
+<td>{{ .Servers }}</td>
+<td>{{ if .Error }}<span class="error">{{ .Error }}</span> ({{ since .Failed }}){{ else }}none{{ end }}</td>
+</tr>
+{{ end }}</table>
+</body>
+</html>
+`))
+
+// statusPage renders the status page with the current state of the discovery.
+func statusPage(cfg *config.Config, logger log.Logger, state *readiness, disc *Discoverer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		disc.mutex.RLock()
+		projects := disc.projects
+		disc.mutex.RUnlock()
+
+		statuses, refreshed, targets := disc.status.list(projects)
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+
+		if err := statusTemplate.Execute(w, struct {
+			Version   string
+			Revision  string
+			Go        string
+			Engine    string
+			Ready     bool
+			Metrics   string
+			Discovery bool
+			Refreshed time.Time
+			Targets   int
+			Projects  []projectStatus
+		}{
+			Version:   version.String,
+			Revision:  version.Revision,
+			Go:        version.Go,
+			Engine:    cfg.Target.Engine,
+			Ready:     state.ready(),
+			Metrics:   cfg.Server.Path,
+			Discovery: cfg.Target.Engine == "http",
+			Refreshed: refreshed,
+			Targets:   targets,
+			Projects:  statuses,
+		}); err != nil {
+			level.Error(logger).Log(
+				"msg", "Failed to render status page",
+				"err", err,
+			)
+		}
+	}
+}
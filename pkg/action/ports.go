@@ -0,0 +1,122 @@
+package action
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
+)
+
+// portMapping assigns a port to all targets with labels matching the rules.
+type portMapping struct {
+	name  string
+	port  int
+	match map[model.LabelName]*regexp.Regexp
+}
+
+func (p portMapping) matches(group *targetgroup.Group) bool {
+	for label, re := range p.match {
+		if !re.MatchString(string(group.Labels[label])) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// portMapper creates a target group per matching port mapping, groups
+// without any matching mapping are kept untouched.
+type portMapper struct {
+	mappings []portMapping
+}
+
+func (m *portMapper) apply(targets []*targetgroup.Group, current map[string]struct{}) []*targetgroup.Group {
+	result := make([]*targetgroup.Group, 0, len(targets))
+
+	for _, target := range targets {
+		mapped := false
+
+		for _, mapping := range m.mappings {
+			if !mapping.matches(target) {
+				continue
+			}
+
+			host := string(target.Labels[model.AddressLabel])
+
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				host = h
+			}
+
+			addr := model.LabelValue(net.JoinHostPort(host, strconv.Itoa(mapping.port)))
+			labels := target.Labels.Clone()
+			labels[model.AddressLabel] = addr
+
+			if mapping.name != "" {
+				labels[model.LabelName(Labels["mapping"])] = model.LabelValue(mapping.name)
+			}
+
+			group := &targetgroup.Group{
+				Source: fmt.Sprintf("%s:%d", target.Source, mapping.port),
+				Targets: []model.LabelSet{
+					{
+						model.AddressLabel: addr,
+					},
+				},
+				Labels: labels,
+			}
+
+			current[group.Source] = struct{}{}
+			result = append(result, group)
+			mapped = true
+		}
+
+		if mapped {
+			delete(current, target.Source)
+			continue
+		}
+
+		result = append(result, target)
+	}
+
+	return result
+}
+
+func newPortMapper(cfg []config.PortMapping) (*portMapper, error) {
+	if len(cfg) == 0 {
+		return nil, nil
+	}
+
+	m := &portMapper{
+		mappings: make([]portMapping, 0, len(cfg)),
+	}
+
+	for _, row := range cfg {
+		if row.Port <= 0 || row.Port > 65535 {
+			return nil, fmt.Errorf("invalid port %d for port mapping", row.Port)
+		}
+
+		mapping := portMapping{
+			name:  row.Name,
+			port:  row.Port,
+			match: make(map[model.LabelName]*regexp.Regexp, len(row.Match)),
+		}
+
+		for label, value := range row.Match {
+			re, err := regexp.Compile("^(?:" + value + ")$")
+
+			if err != nil {
+				return nil, err
+			}
+
+			mapping.match[model.LabelName(label)] = re
+		}
+
+		m.mappings = append(m.mappings, mapping)
+	}
+
+	return m, nil
+}
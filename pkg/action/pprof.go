@@ -0,0 +1,29 @@
+package action
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/go-kit/log"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/middleware"
+)
+
+// profiler provides the pprof handlers, they are mounted below /debug.
+func profiler() http.Handler {
+	return chimiddleware.Profiler()
+}
+
+// profilerHandler provides the handler for the separate pprof server.
+func profilerHandler(cfg *config.Config, logger log.Logger) *chi.Mux {
+	mux := chi.NewRouter()
+
+	if networks := allowedNetworks(cfg.Server.Allow); len(networks) > 0 {
+		mux.Use(middleware.Allow(networks, logger))
+	}
+
+	mux.Mount("/debug", profiler())
+
+	return mux
+}
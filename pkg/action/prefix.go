@@ -0,0 +1,30 @@
+package action
+
+import (
+	"strings"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+)
+
+// prefixLabel converts a label of the default provider prefix into a label
+// with the configured prefix.
+func prefixLabel(prefix, label string) string {
+	if !strings.HasPrefix(label, providerPrefix) {
+		return label
+	}
+
+	return model.MetaLabelPrefix + prefix + strings.TrimPrefix(label, providerPrefix)
+}
+
+// prefixGroup renames all labels of the default provider prefix within the
+// group to the configured prefix.
+func prefixGroup(prefix string, group *targetgroup.Group) {
+	result := make(model.LabelSet, len(group.Labels))
+
+	for key, value := range group.Labels {
+		result[model.LabelName(prefixLabel(prefix, string(key)))] = value
+	}
+
+	group.Labels = result
+}
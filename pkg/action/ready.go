@@ -0,0 +1,65 @@
+package action
+
+import (
+	"sync/atomic"
+
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/writer"
+)
+
+// readiness tracks if the discovery has been refreshed and if all outputs
+// have been written successfully at least once.
+type readiness struct {
+	refreshed uint32
+	pending   int32
+}
+
+// refresh marks the discovery as successfully refreshed.
+func (r *readiness) refresh() {
+	if r == nil {
+		return
+	}
+
+	atomic.StoreUint32(&r.refreshed, 1)
+}
+
+// ready returns if the discovery and all outputs have succeeded.
+func (r *readiness) ready() bool {
+	return atomic.LoadUint32(&r.refreshed) == 1 && atomic.LoadInt32(&r.pending) <= 0
+}
+
+// wrap tracks the first successful write of all writers.
+func (r *readiness) wrap(writers []writer.Writer) []writer.Writer {
+	result := make([]writer.Writer, 0, len(writers))
+
+	for _, w := range writers {
+		atomic.AddInt32(&r.pending, 1)
+
+		result = append(result, &readyWriter{
+			Writer: w,
+			state:  r,
+		})
+	}
+
+	return result
+}
+
+// readyWriter reports the first successful write to the readiness.
+type readyWriter struct {
+	writer.Writer
+
+	state   *readiness
+	written uint32
+}
+
+// Write implements the writer interface.
+func (w *readyWriter) Write(groups []writer.Group) error {
+	if err := w.Writer.Write(groups); err != nil {
+		return err
+	}
+
+	if atomic.CompareAndSwapUint32(&w.written, 0, 1) {
+		atomic.AddInt32(&w.state.pending, -1)
+	}
+
+	return nil
+}
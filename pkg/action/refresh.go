@@ -0,0 +1,66 @@
+package action
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/go-kit/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// triggerKey defines the context key for the trigger of a refresh.
+type triggerKey struct{}
+
+// refreshKey defines the context key for the ID of a refresh.
+type refreshKey struct{}
+
+// withTrigger attaches the trigger of a refresh to the context.
+func withTrigger(ctx context.Context, trigger string) context.Context {
+	return context.WithValue(ctx, triggerKey{}, trigger)
+}
+
+// triggerFrom returns the trigger of a refresh, refreshes without a trigger
+// have been requested manually like by the list command.
+func triggerFrom(ctx context.Context) string {
+	if trigger, ok := ctx.Value(triggerKey{}).(string); ok {
+		return trigger
+	}
+
+	return "manual"
+}
+
+// withRefresh attaches a new refresh ID to the context, if the refresh gets
+// traced the trace ID is used to correlate the logs with the trace.
+func withRefresh(ctx context.Context, span trace.Span) context.Context {
+	if sc := span.SpanContext(); sc.HasTraceID() && sc.IsSampled() {
+		return context.WithValue(ctx, refreshKey{}, sc.TraceID().String())
+	}
+
+	id := make([]byte, 8)
+
+	if _, err := rand.Read(id); err != nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, refreshKey{}, hex.EncodeToString(id))
+}
+
+// refreshFrom returns the refresh ID of the context if there is any.
+func refreshFrom(ctx context.Context) string {
+	if id, ok := ctx.Value(refreshKey{}).(string); ok {
+		return id
+	}
+
+	return ""
+}
+
+// refreshLogger returns the logger of the discoverer with the refresh ID of
+// the context attached to all log lines.
+func (d *Discoverer) refreshLogger(ctx context.Context) log.Logger {
+	if id := refreshFrom(ctx); id != "" {
+		return log.With(d.logger, "refresh", id)
+	}
+
+	return d.logger
+}
@@ -0,0 +1,80 @@
+package action
+
+import (
+	"fmt"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/relabel"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
+	"gopkg.in/yaml.v2"
+)
+
+// relabeler applies relabel rules to the target groups before writing them.
+type relabeler struct {
+	rules []*relabel.Config
+}
+
+// apply processes the labels of the group, it returns false if the group
+// should be dropped.
+func (r *relabeler) apply(group *targetgroup.Group) bool {
+	lset := make(labels.Labels, 0, len(group.Labels))
+
+	for key, value := range group.Labels {
+		lset = append(lset, labels.Label{
+			Name:  string(key),
+			Value: string(value),
+		})
+	}
+
+	result := relabel.Process(labels.New(lset...), r.rules...)
+
+	if result == nil {
+		return false
+	}
+
+	group.Labels = make(model.LabelSet, len(result))
+
+	for _, label := range result {
+		group.Labels[model.LabelName(label.Name)] = model.LabelValue(label.Value)
+	}
+
+	if addr, ok := group.Labels[model.AddressLabel]; ok {
+		for _, target := range group.Targets {
+			target[model.AddressLabel] = addr
+		}
+	}
+
+	return true
+}
+
+// newRelabeler converts the rules into relabel configs, this way we get the
+// same defaults and validation like within Prometheus itself.
+func newRelabeler(rules []config.Relabel) (*relabeler, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	r := &relabeler{
+		rules: make([]*relabel.Config, 0, len(rules)),
+	}
+
+	for i, rule := range rules {
+		content, err := yaml.Marshal(rule)
+
+		if err != nil {
+			return nil, err
+		}
+
+		result := &relabel.Config{}
+
+		if err := yaml.Unmarshal(content, result); err != nil {
+			return nil, fmt.Errorf("invalid relabel rule %d: %w", i, err)
+		}
+
+		r.rules = append(r.rules, result)
+	}
+
+	return r, nil
+}
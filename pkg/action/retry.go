@@ -0,0 +1,47 @@
+package action
+
+import (
+	"context"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
+)
+
+// retrier wraps the backoff used to retry requests failed with a server error
+// or a network error like a timeout, it counts the retries per project.
+type retrier struct {
+	backoff.BackOff
+	project string
+}
+
+// NextBackOff implements the backoff interface.
+func (r *retrier) NextBackOff() time.Duration {
+	next := r.BackOff.NextBackOff()
+
+	if next != backoff.Stop {
+		requestRetries.WithLabelValues(r.project).Inc()
+	}
+
+	return next
+}
+
+// newRetrier builds an exponential backoff with jitter limited to the number
+// of attempts and the context, a single attempt disables the retries.
+func newRetrier(ctx context.Context, project string, cfg config.Retry) backoff.BackOff {
+	if cfg.Attempts <= 1 {
+		return &backoff.StopBackOff{}
+	}
+
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = cfg.Initial.Duration()
+	b.Multiplier = cfg.Multiplier
+	b.MaxInterval = cfg.Max.Duration()
+	b.MaxElapsedTime = 0
+	b.Reset()
+
+	return &retrier{
+		BackOff: backoff.WithContext(backoff.WithMaxRetries(b, uint64(cfg.Attempts-1)), ctx),
+		project: project,
+	}
+}
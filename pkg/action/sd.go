@@ -0,0 +1,235 @@
+package action
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/adapter"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/writer"
+)
+
+// sdHandler serves the written service discovery data. If bearer tokens are
+// defined the groups get filtered by the projects granted to the token, the
+// project route only serves the groups of a single project.
+func sdHandler(cfg *config.Config, logger log.Logger) http.HandlerFunc {
+	label := prefixLabel(cfg.Target.Prefix, Labels["project"])
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		project := chi.URLParam(r, "project")
+		projects, ok := grantedProjects(cfg.Server.Tokens, r, logger)
+
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="prometheus-hetzner-sd"`)
+
+			http.Error(
+				w,
+				http.StatusText(http.StatusUnauthorized),
+				http.StatusUnauthorized,
+			)
+
+			return
+		}
+
+		if project != "" {
+			if projects != nil && !projects[project] {
+				http.Error(
+					w,
+					http.StatusText(http.StatusForbidden),
+					http.StatusForbidden,
+				)
+
+				return
+			}
+
+			projects = map[string]bool{project: true}
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		content, err := ioutil.ReadFile(cfg.Target.File)
+
+		if err != nil {
+			level.Info(logger).Log(
+				"msg", "Failed to read service discovery data",
+				"err", err,
+			)
+
+			http.Error(
+				w,
+				"Failed to read service discovery data",
+				http.StatusInternalServerError,
+			)
+
+			return
+		}
+
+		if projects != nil {
+			content, err = filterProjects(content, label, projects)
+
+			if err != nil {
+				level.Info(logger).Log(
+					"msg", "Failed to filter service discovery data",
+					"err", err,
+				)
+
+				http.Error(
+					w,
+					"Failed to filter service discovery data",
+					http.StatusInternalServerError,
+				)
+
+				return
+			}
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write(content)
+	}
+}
+
+// debugHandler serves the current target groups of the adapter. The bearer
+// tokens are checked like for the service discovery, if no tokens are defined
+// the basic auth of the web config protects the endpoint.
+func debugHandler(cfg *config.Config, logger log.Logger, a *adapter.Adapter) http.HandlerFunc {
+	label := prefixLabel(cfg.Target.Prefix, Labels["project"])
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		projects, ok := grantedProjects(cfg.Server.Tokens, r, logger)
+
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="prometheus-hetzner-sd"`)
+
+			http.Error(
+				w,
+				http.StatusText(http.StatusUnauthorized),
+				http.StatusUnauthorized,
+			)
+
+			return
+		}
+
+		groups := a.Groups()
+
+		if projects != nil {
+			groups = grantedGroups(groups, label, projects)
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		content, err := json.MarshalIndent(groups, "", "  ")
+
+		if err != nil {
+			level.Info(logger).Log(
+				"msg", "Failed to encode target groups",
+				"err", err,
+			)
+
+			http.Error(
+				w,
+				"Failed to encode target groups",
+				http.StatusInternalServerError,
+			)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write(content)
+	}
+}
+
+// grantedProjects checks the bearer token of the request, it returns the
+// granted projects or nil if all projects are granted.
+func grantedProjects(tokens []config.Token, r *http.Request, logger log.Logger) (map[string]bool, bool) {
+	if len(tokens) == 0 {
+		return nil, true
+	}
+
+	auth := r.Header.Get("Authorization")
+
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return nil, false
+	}
+
+	given := []byte(strings.TrimPrefix(auth, "Bearer "))
+
+	for _, token := range tokens {
+		value, err := token.Secret()
+
+		if err != nil {
+			level.Error(logger).Log(
+				"msg", "Failed to read bearer token",
+				"file", token.TokenFile,
+				"err", err,
+			)
+
+			continue
+		}
+
+		if value == "" || subtle.ConstantTimeCompare([]byte(value), given) != 1 {
+			continue
+		}
+
+		if len(token.Projects) == 0 {
+			return nil, true
+		}
+
+		result := make(map[string]bool, len(token.Projects))
+
+		for _, project := range token.Projects {
+			result[project] = true
+		}
+
+		return result, true
+	}
+
+	return nil, false
+}
+
+// filterProjects drops all groups containing targets of other projects,
+// groups without a project label are dropped as well.
+func filterProjects(content []byte, label string, projects map[string]bool) ([]byte, error) {
+	groups := make([]writer.Group, 0)
+
+	if err := json.Unmarshal(content, &groups); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(grantedGroups(groups, label, projects))
+}
+
+// grantedGroups returns the groups of the granted projects, groups of multiple
+// projects are only returned if all projects are granted.
+func grantedGroups(groups []writer.Group, label string, projects map[string]bool) []writer.Group {
+	result := make([]writer.Group, 0, len(groups))
+
+	for _, group := range groups {
+		value, ok := group.Labels[label]
+
+		if !ok || value == "" {
+			continue
+		}
+
+		granted := true
+
+		for _, project := range strings.Split(value, ",") {
+			if !projects[project] {
+				granted = false
+				break
+			}
+		}
+
+		if granted {
+			result = append(result, group)
+		}
+	}
+
+	return result
+}
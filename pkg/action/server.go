@@ -3,16 +3,17 @@ package action
 import (
 	"context"
 	"io"
-	"io/ioutil"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
 	"time"
 
-	"github.com/appscode/go-hetzner"
 	"github.com/go-chi/chi/v5"
-	"github.com/go-kit/kit/log"
-	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
 	"github.com/oklog/run"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/prometheus/exporter-toolkit/web"
@@ -20,6 +21,7 @@ import (
 	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
 	"github.com/promhippie/prometheus-hetzner-sd/pkg/middleware"
 	"github.com/promhippie/prometheus-hetzner-sd/pkg/version"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/writer"
 )
 
 // Server handles the server sub-command.
@@ -33,47 +35,147 @@ func Server(cfg *config.Config, logger log.Logger) error {
 		"engine", cfg.Target.Engine,
 	)
 
+	shutdown, err := setupTracing(cfg)
+
+	if err != nil {
+		level.Error(logger).Log(
+			"msg", "Failed to initialize tracing",
+			"err", err,
+		)
+
+		return err
+	}
+
+	defer shutdown()
+
 	var gr run.Group
+	state := &readiness{}
+	disc, err := newDiscoverer(cfg, logger)
 
-	{
-		ctx := context.Background()
-		clients := make(map[string]*hetzner.Client, len(cfg.Target.Credentials))
+	if err != nil {
+		level.Error(logger).Log(
+			"msg", "Failed to initialize discoverer",
+			"err", err,
+		)
 
-		for _, credential := range cfg.Target.Credentials {
-			clients[credential.Project] = hetzner.NewClient(
-				credential.Username,
-				credential.Password,
-			)
-		}
+		return err
+	}
 
-		disc := Discoverer{
-			clients: clients,
-			logger:  logger,
-			refresh: cfg.Target.Refresh,
-			lasts:   make(map[string]struct{}),
-		}
+	out, err := outputs(cfg, logger)
 
-		a := adapter.NewAdapter(ctx, cfg.Target.File, "hetzner-sd", disc, logger)
+	if err != nil {
+		level.Error(logger).Log(
+			"msg", "Failed to initialize outputs",
+			"err", err,
+		)
+
+		return err
+	}
+
+	disc.ready = state
+	disc.snapshot = cfg.Target.State
+
+	if restored, err := disc.restore(); err != nil {
+		level.Warn(logger).Log(
+			"msg", "Failed to restore state",
+			"file", cfg.Target.State,
+			"err", err,
+		)
+	} else if restored > 0 {
+		level.Info(logger).Log(
+			"msg", "Restored previous targets",
+			"file", cfg.Target.State,
+			"projects", restored,
+		)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	a := adapter.NewAdapter(
+		ctx,
+		state.wrap(out.writers),
+		cfg.Target.Interval.Duration(),
+		"hetzner-sd",
+		disc,
+		logger,
+	)
+
+	gr.Add(func() error {
 		a.Run()
+		a.Wait()
+
+		return nil
+	}, func(reason error) {
+		cancel()
+
+		a.Wait()
+		out.close(logger)
+	})
+
+	if out.dns != nil {
+		gr.Add(func() error {
+			level.Info(logger).Log(
+				"msg", "Starting DNS server",
+				"addr", cfg.Target.DNS.Addr,
+			)
+
+			return out.dns.Serve()
+		}, func(reason error) {
+			out.dns.Stop()
+
+			level.Info(logger).Log(
+				"msg", "DNS server shutdown",
+				"reason", reason,
+			)
+		})
+	}
+
+	if cfg.Watch && len(watchFiles(cfg)) > 0 {
+		gr.Add(func() error {
+			return watchConfig(ctx, cfg, disc, logger)
+		}, func(reason error) {
+			cancel()
+		})
 	}
 
 	{
 		server := &http.Server{
 			Addr:         cfg.Server.Addr,
-			Handler:      handler(cfg, logger),
+			Handler:      handler(cfg, logger, state, disc, a),
 			ReadTimeout:  5 * time.Second,
 			WriteTimeout: 10 * time.Second,
 		}
 
 		gr.Add(func() error {
+			if cfg.Server.Systemd {
+				l, err := systemdListener()
+
+				if err != nil {
+					return err
+				}
+
+				level.Info(logger).Log(
+					"msg", "Starting metrics server",
+					"addr", l.Addr().String(),
+					"systemd", true,
+				)
+
+				return web.Serve(l, server, cfg.Server.Web, logger)
+			}
+
 			level.Info(logger).Log(
 				"msg", "Starting metrics server",
 				"addr", cfg.Server.Addr,
 			)
 
-			return web.ListenAndServe(server, cfg.Server.Web, logger)
+			l, err := listener(cfg.Server.Addr)
+
+			if err != nil {
+				return err
+			}
+
+			return web.Serve(l, server, cfg.Server.Web, logger)
 		}, func(reason error) {
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.Shutdown.Duration())
 			defer cancel()
 
 			if err := server.Shutdown(ctx); err != nil {
@@ -92,11 +194,51 @@ func Server(cfg *config.Config, logger log.Logger) error {
 		})
 	}
 
+	if cfg.Server.Pprof && cfg.Server.PprofAddr != "" {
+		server := &http.Server{
+			Addr:        cfg.Server.PprofAddr,
+			Handler:     profilerHandler(cfg, logger),
+			ReadTimeout: 5 * time.Second,
+		}
+
+		gr.Add(func() error {
+			level.Info(logger).Log(
+				"msg", "Starting pprof server",
+				"addr", cfg.Server.PprofAddr,
+			)
+
+			l, err := listener(cfg.Server.PprofAddr)
+
+			if err != nil {
+				return err
+			}
+
+			return web.Serve(l, server, cfg.Server.Web, logger)
+		}, func(reason error) {
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.Shutdown.Duration())
+			defer cancel()
+
+			if err := server.Shutdown(ctx); err != nil {
+				level.Error(logger).Log(
+					"msg", "Failed to shutdown pprof gracefully",
+					"err", err,
+				)
+
+				return
+			}
+
+			level.Info(logger).Log(
+				"msg", "Pprof shutdown gracefully",
+				"reason", reason,
+			)
+		})
+	}
+
 	{
 		stop := make(chan os.Signal, 1)
 
 		gr.Add(func() error {
-			signal.Notify(stop, os.Interrupt)
+			signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 
 			<-stop
 
@@ -109,17 +251,165 @@ func Server(cfg *config.Config, logger log.Logger) error {
 	return gr.Run()
 }
 
-func handler(cfg *config.Config, logger log.Logger) *chi.Mux {
+// outputList defines the writers of all outputs, the embedded DNS server gets
+// served by its own actor and the closers get closed on shutdown.
+type outputList struct {
+	writers []writer.Writer
+	dns     *writer.DNS
+	closers []io.Closer
+}
+
+// close closes all connections of the outputs.
+func (o *outputList) close(logger log.Logger) {
+	for _, c := range o.closers {
+		if err := c.Close(); err != nil {
+			level.Warn(logger).Log(
+				"msg", "Failed to close output",
+				"err", err,
+			)
+		}
+	}
+}
+
+func outputs(cfg *config.Config, logger log.Logger) (*outputList, error) {
+	result := &outputList{}
+	names := make(map[string]int)
+
+	for _, o := range cfg.Target.AllOutputs() {
+		w, err := output(cfg, o, logger)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if c, ok := w.(io.Closer); ok {
+			result.closers = append(result.closers, c)
+		}
+
+		switch o.GroupBy {
+		case "datacenter":
+			w = writer.NewGroupBy(w, prefixLabel(cfg.Target.Prefix, Labels["dc"]))
+		case "project":
+			w = writer.NewGroupBy(w, prefixLabel(cfg.Target.Prefix, Labels["project"]))
+		case "none":
+			w = writer.NewGroupBy(w, "")
+		}
+
+		w, err = writer.NewFilter(observed(w, o.Engine, names), o.Filters)
+
+		if err != nil {
+			return nil, err
+		}
+
+		result.writers = append(result.writers, w)
+	}
+
+	if cfg.Target.Webhook.URL != "" {
+		result.writers = append(result.writers, observed(writer.NewWebhook(cfg.Target.Webhook), "webhook", names))
+	}
+
+	if cfg.Target.DNS.Addr != "" {
+		d, err := writer.NewDNS(
+			cfg.Target.DNS,
+			writer.DNSLabels{
+				Name:       prefixLabel(cfg.Target.Prefix, Labels["name"]),
+				Datacenter: prefixLabel(cfg.Target.Prefix, Labels["dc"]),
+			},
+			logger,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		result.dns = d
+		result.writers = append(result.writers, observed(d, "dns", names))
+	}
+
+	if cfg.Target.Nats.URL != "" {
+		w, err := writer.NewNats(cfg.Target.Nats)
+
+		if err != nil {
+			return nil, err
+		}
+
+		result.writers = append(result.writers, observed(w, "nats", names))
+	}
+
+	return result, nil
+}
+
+func output(cfg *config.Config, o config.Output, logger log.Logger) (writer.Writer, error) {
+	switch o.Engine {
+	case "zookeeper":
+		return writer.NewZookeeper(o.Zookeeper, logger)
+	case "kubernetes":
+		return writer.NewKubernetes(o.Kubernetes)
+	case "s3":
+		return writer.NewS3(o.S3)
+	case "redis":
+		return writer.NewRedis(o.Redis), nil
+	}
+
+	indent := o.Indent
+
+	if o.Compact {
+		indent = 0
+	}
+
+	return writer.NewFile(
+		outputFile(o.File, o.Split),
+		cfg.Target.Vars,
+		writer.FileLabels{
+			Project:    prefixLabel(cfg.Target.Prefix, Labels["project"]),
+			Datacenter: prefixLabel(cfg.Target.Prefix, Labels["dc"]),
+			Location:   prefixLabel(cfg.Target.Prefix, Labels["location"]),
+		},
+		indent,
+	)
+}
+
+// outputFile converts the split option into a templated file name.
+func outputFile(file, split string) string {
+	ext := filepath.Ext(file)
+
+	switch split {
+	case "datacenter":
+		return strings.TrimSuffix(file, ext) + "-{{ .Datacenter }}" + ext
+	case "location":
+		return strings.TrimSuffix(file, ext) + "-{{ .Location }}" + ext
+	}
+
+	return file
+}
+
+func handler(cfg *config.Config, logger log.Logger, state *readiness, disc *Discoverer, a *adapter.Adapter) *chi.Mux {
 	mux := chi.NewRouter()
 	mux.Use(middleware.Recoverer(logger))
+
+	if networks := allowedNetworks(cfg.Server.Allow); len(networks) > 0 {
+		mux.Use(middleware.Allow(networks, logger))
+	}
+
 	mux.Use(middleware.RealIP)
+
+	if cfg.Server.AccessLog {
+		mux.Use(middleware.AccessLog(logger))
+	}
+
+	if len(cfg.Server.CORS.Origins) > 0 {
+		mux.Use(middleware.CORS(cfg.Server.CORS.Origins, cfg.Server.CORS.Methods))
+	}
+
 	mux.Use(middleware.Timeout)
 	mux.Use(middleware.Cache)
+	mux.Use(middleware.Compress)
 
 	prom := promhttp.HandlerFor(
 		registry,
 		promhttp.HandlerOpts{
-			ErrorLog: promLogger{logger},
+			ErrorLog:          promLogger{logger},
+			EnableOpenMetrics: true,
 		},
 	)
 
@@ -128,45 +418,81 @@ func handler(cfg *config.Config, logger log.Logger) *chi.Mux {
 			prom.ServeHTTP(w, r)
 		})
 
-		root.Get("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		healthy := func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Content-Type", "text/plain")
 			w.WriteHeader(http.StatusOK)
 
 			io.WriteString(w, http.StatusText(http.StatusOK))
-		})
+		}
 
-		root.Get("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		ready := func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Content-Type", "text/plain")
-			w.WriteHeader(http.StatusOK)
 
+			if !state.ready() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				io.WriteString(w, http.StatusText(http.StatusServiceUnavailable))
+
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
 			io.WriteString(w, http.StatusText(http.StatusOK))
-		})
+		}
 
-		if cfg.Target.Engine == "http" {
-			root.Get("/sd", func(w http.ResponseWriter, r *http.Request) {
-				w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		root.Get("/healthz", healthy)
+		root.Get("/-/healthy", healthy)
+		root.Get("/readyz", ready)
+		root.Get("/-/ready", ready)
 
-				content, err := ioutil.ReadFile(cfg.Target.File)
+		if cfg.Server.Path != "/" {
+			root.Get("/", statusPage(cfg, logger, state, disc))
+		}
 
-				if err != nil {
-					level.Info(logger).Log(
-						"msg", "Failed to read service discovery data",
+		if cfg.Server.Debug {
+			root.Get("/debug/targets", debugHandler(cfg, logger, a))
+		}
+
+		if cfg.Server.Pprof && cfg.Server.PprofAddr == "" {
+			root.Mount("/debug", profiler())
+		}
+
+		if cfg.Server.Lifecycle {
+			root.Post("/-/reload", func(w http.ResponseWriter, r *http.Request) {
+				if err := reloadConfig(cfg, disc); err != nil {
+					configReloadFailures.Inc()
+
+					level.Error(logger).Log(
+						"msg", "Failed to reload config",
 						"err", err,
 					)
 
 					http.Error(
 						w,
-						"Failed to read service discovery data",
+						"Failed to reload config",
 						http.StatusInternalServerError,
 					)
 
 					return
 				}
 
+				configReloads.Inc()
+
+				level.Info(logger).Log(
+					"msg", "Reloaded config",
+					"source", "endpoint",
+				)
+
+				w.Header().Set("Content-Type", "text/plain")
 				w.WriteHeader(http.StatusOK)
-				w.Write(content)
+
+				io.WriteString(w, http.StatusText(http.StatusOK))
 			})
 		}
+
+		if cfg.Target.Engine == "http" {
+			root.Get("/sd", sdHandler(cfg, logger))
+			root.Get("/sd/{project}", sdHandler(cfg, logger))
+		}
 	})
 
 	return mux
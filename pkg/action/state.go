@@ -0,0 +1,112 @@
+package action
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/appscode/go-hetzner"
+	"github.com/prometheus/common/model"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/writer"
+)
+
+// stateProject defines the persisted servers and subnets of a project.
+type stateProject struct {
+	Refreshed time.Time                `json:"refreshed"`
+	Servers   []*hetzner.ServerSummary `json:"servers"`
+	Subnets   map[int][]subnet         `json:"subnets,omitempty"`
+}
+
+// stateFile defines the persisted results of the last successful refreshes,
+// they are loaded on startup to serve targets even if the API is unavailable.
+// The labels of the last targets are used to detect the changes since then.
+type stateFile struct {
+	Projects map[string]stateProject   `json:"projects"`
+	Labels   map[string]model.LabelSet `json:"labels,omitempty"`
+}
+
+// restore loads the persisted results of the previous run for all configured
+// projects, a missing state file is not treated as an error.
+func (d *Discoverer) restore() (int, error) {
+	if d.snapshot == "" {
+		return 0, nil
+	}
+
+	content, err := ioutil.ReadFile(d.snapshot)
+
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	state := stateFile{}
+
+	if err := json.Unmarshal(content, &state); err != nil {
+		return 0, err
+	}
+
+	d.mutex.RLock()
+	accounts := d.accounts
+	d.mutex.RUnlock()
+
+	cache := make(map[string]*fetched, len(state.Projects))
+
+	for project, result := range state.Projects {
+		acc, ok := accounts[project]
+
+		if !ok {
+			continue
+		}
+
+		subnets := result.Subnets
+
+		if subnets == nil {
+			subnets = make(map[int][]subnet)
+		}
+
+		cache[project] = &fetched{
+			account: acc,
+			servers: result.Servers,
+			subnets: subnets,
+			at:      result.Refreshed,
+		}
+	}
+
+	d.cache = cache
+	d.labels = state.Labels
+
+	return len(cache), nil
+}
+
+// persist writes the results of the last successful refreshes to the state
+// file, the file gets replaced atomically to never persist a partial state.
+func (d *Discoverer) persist() error {
+	if d.snapshot == "" {
+		return nil
+	}
+
+	state := stateFile{
+		Projects: make(map[string]stateProject, len(d.cache)),
+		Labels:   d.labels,
+	}
+
+	for project, result := range d.cache {
+		state.Projects[project] = stateProject{
+			Refreshed: result.at.UTC(),
+			Servers:   result.servers,
+			Subnets:   result.subnets,
+		}
+	}
+
+	content, err := json.Marshal(state)
+
+	if err != nil {
+		return err
+	}
+
+	return writer.WriteAtomic(d.snapshot, content)
+}
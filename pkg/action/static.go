@@ -0,0 +1,41 @@
+package action
+
+import (
+	"fmt"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
+)
+
+// staticTargets converts the static targets into target groups, they are
+// merged into the output without any further processing.
+func staticTargets(cfg []config.StaticTarget) ([]*targetgroup.Group, error) {
+	result := make([]*targetgroup.Group, 0, len(cfg))
+
+	for i, static := range cfg {
+		group := &targetgroup.Group{
+			Source:  fmt.Sprintf("static/%d", i),
+			Targets: make([]model.LabelSet, 0, len(static.Targets)),
+			Labels:  make(model.LabelSet, len(static.Labels)),
+		}
+
+		for _, target := range static.Targets {
+			group.Targets = append(group.Targets, model.LabelSet{
+				model.AddressLabel: model.LabelValue(target),
+			})
+		}
+
+		for key, value := range static.Labels {
+			if !model.LabelName(key).IsValid() {
+				return nil, fmt.Errorf("invalid label name %q for static targets", key)
+			}
+
+			group.Labels[model.LabelName(key)] = model.LabelValue(value)
+		}
+
+		result = append(result, group)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,86 @@
+package action
+
+import (
+	"sync"
+	"time"
+)
+
+// projectStatus defines the outcome of the last refreshes of a project.
+type projectStatus struct {
+	Project   string
+	Refreshed time.Time
+	Servers   int
+	Failed    time.Time
+	Error     string
+}
+
+// status tracks the refreshes of the discovery for the status page.
+type status struct {
+	projects  map[string]projectStatus
+	refreshed time.Time
+	targets   int
+	mutex     sync.RWMutex
+}
+
+func newStatus() *status {
+	return &status{
+		projects: make(map[string]projectStatus),
+	}
+}
+
+// succeed records a successful refresh of the project.
+func (s *status) succeed(project string, servers int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	current := s.projects[project]
+	current.Project = project
+	current.Refreshed = time.Now()
+	current.Servers = servers
+
+	s.projects[project] = current
+}
+
+// fail records a failed refresh of the project.
+func (s *status) fail(project string, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	current := s.projects[project]
+	current.Project = project
+	current.Failed = time.Now()
+	current.Error = err.Error()
+
+	s.projects[project] = current
+}
+
+// update records the number of targets of a successful refresh.
+func (s *status) update(targets int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.refreshed = time.Now()
+	s.targets = targets
+}
+
+// list returns the status of the given projects and the latest target count.
+func (s *status) list(projects []string) ([]projectStatus, time.Time, int) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	result := make([]projectStatus, 0, len(projects))
+
+	for _, project := range projects {
+		current, ok := s.projects[project]
+
+		if !ok {
+			current = projectStatus{
+				Project: project,
+			}
+		}
+
+		result = append(result, current)
+	}
+
+	return result, s.refreshed, s.targets
+}
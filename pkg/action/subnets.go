@@ -0,0 +1,108 @@
+package action
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/appscode/go-hetzner"
+)
+
+type subnetResponse struct {
+	Subnet subnet `json:"subnet"`
+}
+
+type subnet struct {
+	IP           string `json:"ip"`
+	Mask         int    `json:"mask"`
+	Gateway      string `json:"gateway"`
+	ServerIP     string `json:"server_ip"`
+	ServerNumber int    `json:"server_number"`
+}
+
+// String returns the subnet in CIDR notation.
+func (s subnet) String() string {
+	return fmt.Sprintf("%s/%d", s.IP, s.Mask)
+}
+
+// addresses returns all usable IPv4 addresses of the subnet, excluding the
+// network, gateway and broadcast addresses and all excluded networks.
+func (s subnet) addresses(excludes []*net.IPNet) []string {
+	_, network, err := net.ParseCIDR(s.String())
+
+	if err != nil {
+		return []string{}
+	}
+
+	ip := network.IP.To4()
+
+	if ip == nil {
+		return []string{}
+	}
+
+	for _, exclude := range excludes {
+		if exclude.Contains(ip) {
+			if ones, _ := exclude.Mask.Size(); ones <= s.Mask {
+				return []string{}
+			}
+		}
+	}
+
+	gateway := net.ParseIP(s.Gateway)
+	first := binary.BigEndian.Uint32(ip)
+	last := first | ^binary.BigEndian.Uint32(net.IP(network.Mask).To4())
+	result := make([]string, 0)
+
+	for i := first + 1; i < last; i++ {
+		current := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(current, i)
+
+		if gateway != nil && gateway.Equal(current) {
+			continue
+		}
+
+		if excluded(current, excludes) {
+			continue
+		}
+
+		result = append(result, current.String())
+	}
+
+	return result
+}
+
+func excluded(ip net.IP, excludes []*net.IPNet) bool {
+	for _, exclude := range excludes {
+		if exclude.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func listSubnets(ctx context.Context, a *account) (map[int][]subnet, *http.Response, error) {
+	records := make([]subnetResponse, 0)
+	result := make(map[int][]subnet)
+
+	resp, err := a.api.get(ctx, a, "/subnet", &records)
+
+	if err != nil {
+		if apiErr, ok := err.(*hetzner.APIError); ok && apiErr.Status == http.StatusNotFound {
+			return result, resp, nil
+		}
+
+		return nil, resp, err
+	}
+
+	for _, record := range records {
+		result[record.Subnet.ServerNumber] = append(
+			result[record.Subnet.ServerNumber],
+			record.Subnet,
+		)
+	}
+
+	return result, resp, nil
+}
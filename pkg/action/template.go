@@ -0,0 +1,75 @@
+package action
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"text/template"
+
+	"github.com/prometheus/common/model"
+)
+
+// targetData defines the data available within target templates.
+type targetData struct {
+	Address    string
+	IPv4       string
+	Name       string
+	Number     string
+	Project    string
+	Product    string
+	Datacenter string
+	Location   string
+	Labels     map[string]string
+}
+
+// RDNS resolves the reverse DNS name of the target, it falls back to the
+// address if the lookup fails.
+func (t targetData) RDNS() string {
+	host := t.Address
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	names, err := net.LookupAddr(stripZone(host))
+
+	if err != nil || len(names) == 0 {
+		return t.Address
+	}
+
+	return strings.TrimSuffix(names[0], ".")
+}
+
+func newTargetData(labels model.LabelSet) targetData {
+	result := targetData{
+		Address:    string(labels[model.AddressLabel]),
+		IPv4:       string(labels[model.LabelName(Labels["ip"])]),
+		Name:       string(labels[model.LabelName(Labels["name"])]),
+		Number:     string(labels[model.LabelName(Labels["number"])]),
+		Project:    string(labels[model.LabelName(Labels["project"])]),
+		Product:    string(labels[model.LabelName(Labels["product"])]),
+		Datacenter: string(labels[model.LabelName(Labels["dc"])]),
+		Location:   string(labels[model.LabelName(Labels["location"])]),
+		Labels:     make(map[string]string, len(labels)),
+	}
+
+	for key, value := range labels {
+		result.Labels[string(key)] = string(value)
+	}
+
+	return result
+}
+
+func parseTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Option("missingkey=zero").Parse(text)
+}
+
+func renderTemplate(tmpl *template.Template, labels model.LabelSet) (string, error) {
+	buf := bytes.NewBufferString("")
+
+	if err := tmpl.Execute(buf, newTargetData(labels)); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,85 @@
+package action
+
+import (
+	"context"
+	"time"
+
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/version"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	// tracer creates the spans of the refreshes, it's a noop until a tracer
+	// provider gets registered by setupTracing.
+	tracer = otel.Tracer("github.com/promhippie/prometheus-hetzner-sd")
+)
+
+// setupTracing registers a tracer provider exporting the spans via OTLP if an
+// endpoint has been configured, the returned function flushes all pending
+// spans on shutdown.
+func setupTracing(cfg *config.Config) (func(), error) {
+	if cfg.Tracing.Endpoint == "" {
+		return func() {}, nil
+	}
+
+	opts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(cfg.Tracing.Endpoint),
+	}
+
+	if cfg.Tracing.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+
+	exporter, err := otlptracehttp.New(context.Background(), opts...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(
+		resource.Default(),
+		resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceNameKey.String("prometheus-hetzner-sd"),
+			semconv.ServiceVersionKey.String(version.String),
+		),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(
+			sdktrace.TraceIDRatioBased(cfg.Tracing.Ratio),
+		)),
+	)
+
+	otel.SetTracerProvider(provider)
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		provider.Shutdown(ctx)
+	}, nil
+}
+
+// failSpan records the error on the span and marks it as failed.
+func failSpan(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
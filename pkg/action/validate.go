@@ -0,0 +1,134 @@
+package action
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/writer"
+)
+
+// Validate checks the credentials, filters, templates and output paths of the
+// configuration without contacting the API.
+func Validate(cfg *config.Config, logger log.Logger) error {
+	projects := make(map[string]struct{}, len(cfg.Target.Credentials))
+
+	for i, credential := range cfg.Target.Credentials {
+		if err := validateCredential(credential); err != nil {
+			level.Error(logger).Log(
+				"msg", "Invalid credentials",
+				"index", i,
+				"project", credential.Project,
+				"err", err,
+			)
+
+			return err
+		}
+
+		if _, ok := projects[credential.Project]; ok {
+			level.Error(logger).Log(
+				"msg", "Duplicate project within credentials",
+				"index", i,
+				"project", credential.Project,
+			)
+
+			return fmt.Errorf("duplicate project %s", credential.Project)
+		}
+
+		projects[credential.Project] = struct{}{}
+	}
+
+	if _, err := newDiscoverer(cfg, logger); err != nil {
+		level.Error(logger).Log(
+			"msg", "Invalid target configuration",
+			"err", err,
+		)
+
+		return err
+	}
+
+	// The http engine serves the written file, so it requires a writable file
+	// like the file engine.
+	for _, o := range cfg.Target.AllOutputs() {
+		if o.Engine != "file" && o.Engine != "http" {
+			continue
+		}
+
+		if _, err := writer.NewFile(o.File, cfg.Target.Vars, writer.FileLabels{}, o.Indent); err != nil {
+			level.Error(logger).Log(
+				"msg", "Invalid template for output.file",
+				"file", o.File,
+				"err", err,
+			)
+
+			return err
+		}
+
+		if err := writable(o.File); err != nil {
+			level.Error(logger).Log(
+				"msg", "Output file is not writable",
+				"file", o.File,
+				"err", err,
+			)
+
+			return err
+		}
+	}
+
+	level.Info(logger).Log(
+		"msg", "Configuration is valid",
+	)
+
+	return nil
+}
+
+// validateCredential checks that all required values of a credential are
+// defined and that referenced secret files are readable.
+func validateCredential(credential config.Credential) error {
+	if credential.Project == "" {
+		return errors.New("missing project name")
+	}
+
+	if credential.Username == "" && credential.UsernameFile == "" {
+		return errors.New("missing username")
+	}
+
+	if credential.Password == "" && credential.PasswordFile == "" && credential.Keyring == "" {
+		return errors.New("missing password")
+	}
+
+	username, password, err := credential.Secrets()
+
+	if err != nil {
+		return err
+	}
+
+	if username == "" || password == "" {
+		return errors.New("empty username or password")
+	}
+
+	return nil
+}
+
+// writable checks if files can be created within the directory of the output
+// file, for templated file names the static part of the path is checked.
+func writable(file string) error {
+	if idx := strings.Index(file, "{{"); idx >= 0 {
+		file = file[:idx]
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(file), ".validate-")
+
+	if err != nil {
+		return err
+	}
+
+	tmp.Close()
+	return os.Remove(tmp.Name())
+}
@@ -0,0 +1,144 @@
+package action
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
+)
+
+const (
+	// watchDelay defines the delay to merge multiple events of a single rewrite.
+	watchDelay = time.Second
+)
+
+// watchFiles returns the configuration file and all secret files of the
+// credentials which should be watched for changes.
+func watchFiles(cfg *config.Config) map[string]struct{} {
+	result := make(map[string]struct{})
+
+	if cfg.File != "" {
+		result[filepath.Clean(cfg.File)] = struct{}{}
+	}
+
+	for _, credential := range cfg.Target.Credentials {
+		for _, file := range credential.Files() {
+			result[filepath.Clean(file)] = struct{}{}
+		}
+	}
+
+	return result
+}
+
+// watchConfig reloads the credentials of the discoverer whenever the
+// configuration file or a secret file changes. The directories get watched to
+// also detect files which are replaced atomically, like mounted secrets within
+// Kubernetes.
+func watchConfig(ctx context.Context, cfg *config.Config, disc *Discoverer, logger log.Logger) error {
+	watcher, err := fsnotify.NewWatcher()
+
+	if err != nil {
+		return err
+	}
+
+	defer watcher.Close()
+
+	files := watchFiles(cfg)
+	dirs := make(map[string]struct{}, len(files))
+
+	for file := range files {
+		dirs[filepath.Dir(file)] = struct{}{}
+
+		level.Info(logger).Log(
+			"msg", "Watching file for changes",
+			"file", file,
+		)
+	}
+
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			return err
+		}
+	}
+
+	var (
+		delay <-chan time.Time
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			if _, ok := files[filepath.Clean(event.Name)]; !ok && filepath.Base(event.Name) != "..data" {
+				continue
+			}
+
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+				continue
+			}
+
+			if delay == nil {
+				delay = time.After(watchDelay)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+
+			level.Error(logger).Log(
+				"msg", "Failed to watch files",
+				"err", err,
+			)
+		case <-delay:
+			delay = nil
+
+			if err := reloadConfig(cfg, disc); err != nil {
+				configReloadFailures.Inc()
+
+				level.Error(logger).Log(
+					"msg", "Failed to reload config",
+					"err", err,
+				)
+
+				continue
+			}
+
+			configReloads.Inc()
+
+			level.Info(logger).Log(
+				"msg", "Reloaded config",
+			)
+		}
+	}
+}
+
+// reloadConfig reads the credentials from the configuration file and passes
+// them together with the credentials defined by flags to the discoverer, the
+// secret files are read again by the discoverer.
+func reloadConfig(cfg *config.Config, disc *Discoverer) error {
+	next := config.Load()
+
+	if err := config.Read(cfg.File, next, !cfg.Lenient); err != nil {
+		return err
+	}
+
+	credentials := append(
+		next.Target.Credentials,
+		cfg.Defaults...,
+	)
+
+	if len(credentials) == 0 {
+		return ErrMissingCredentials
+	}
+
+	return disc.Reload(credentials)
+}
@@ -17,48 +17,56 @@ package adapter
 // NOTE: you do not need to edit this file when implementing a custom sd.
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"os"
-	"path/filepath"
 	"reflect"
+	"sort"
+	"sync"
+	"time"
 
-	"github.com/go-kit/kit/log"
-	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
 	"github.com/prometheus/prometheus/discovery"
 	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/writer"
 )
 
-type customSD struct {
-	Targets []string          `json:"targets"`
-	Labels  map[string]string `json:"labels"`
-}
-
 // Adapter runs an unknown service discovery implementation and converts its target groups
-// to JSON and writes to a file for file_sd.
+// to the file_sd format and passes them to the configured writers.
 type Adapter struct {
-	ctx     context.Context
-	disc    discovery.Discoverer
-	groups  map[string]*customSD
-	manager *discovery.Manager
-	output  string
-	name    string
-	logger  log.Logger
+	ctx      context.Context
+	disc     discovery.Discoverer
+	groups   map[string]*writer.Group
+	manager  *discovery.Manager
+	writers  []writer.Writer
+	interval time.Duration
+	written  time.Time
+	delay    <-chan time.Time
+	initial  chan []*targetgroup.Group
+	name     string
+	logger   log.Logger
+	done     chan struct{}
+	mutex    sync.RWMutex
 }
 
-func mapToArray(m map[string]*customSD) []customSD {
-	arr := make([]customSD, 0, len(m))
-	for _, v := range m {
-		arr = append(arr, *v)
+func mapToArray(m map[string]*writer.Group) []writer.Group {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	// Sort by the group keys to keep a stable order between writes.
+	sort.Strings(keys)
+
+	arr := make([]writer.Group, 0, len(m))
+	for _, k := range keys {
+		arr = append(arr, *m[k])
 	}
 	return arr
 }
 
 // Parses incoming target groups updates. If the update contains changes to the target groups
-// Adapter already knows about, or new target groups, we Marshal to JSON and write to file.
+// Adapter already knows about, or new target groups, we pass them to all writers.
 func (a *Adapter) generateTargetGroups(allTargetGroups map[string][]*targetgroup.Group) {
-	tempGroups := make(map[string]*customSD)
+	tempGroups := make(map[string]*writer.Group)
 	for k, sdTargetGroups := range allTargetGroups {
 		for i, group := range sdTargetGroups {
 			newTargets := make([]string, 0)
@@ -75,51 +83,67 @@ func (a *Adapter) generateTargetGroups(allTargetGroups map[string][]*targetgroup
 			}
 			// Make a unique key, including the current index, in case the sd_type (map key) and group.Source is not unique.
 			key := fmt.Sprintf("%s:%s:%d", k, group.Source, i)
-			tempGroups[key] = &customSD{
+			tempGroups[key] = &writer.Group{
 				Targets: newTargets,
 				Labels:  newLabels,
 			}
 		}
 	}
-	if !reflect.DeepEqual(a.groups, tempGroups) {
+	// Always write the first update, even if it doesn't contain any groups.
+	if !reflect.DeepEqual(a.groups, tempGroups) || a.written.IsZero() {
+		a.mutex.Lock()
 		a.groups = tempGroups
-		err := a.writeOutput()
-		if err != nil {
-			level.Error(log.With(a.logger, "component", "sd-adapter")).Log("err", err)
-		}
+		a.mutex.Unlock()
+
+		a.scheduleOutput()
 	}
 
 }
 
-// Writes JSON formatted targets to output file.
-func (a *Adapter) writeOutput() error {
-	arr := mapToArray(a.groups)
-	b, _ := json.MarshalIndent(arr, "", "    ")
+// Writes the output directly or delays it if the minimum interval between
+// writes has not passed yet, the latest target groups are used when the delay ends.
+func (a *Adapter) scheduleOutput() {
+	if wait := a.interval - time.Since(a.written); a.interval > 0 && wait > 0 {
+		if a.delay == nil {
+			a.delay = time.After(wait)
+		}
 
-	dir, _ := filepath.Split(a.output)
-	tmpfile, err := ioutil.TempFile(dir, "sd-adapter")
-	if err != nil {
-		return err
+		return
 	}
-	defer tmpfile.Close()
 
-	_, err = tmpfile.Write(b)
-	if err != nil {
-		return err
-	}
+	a.writeOutput()
+}
+
+// Passes the current target groups to all configured writers.
+func (a *Adapter) writeOutput() {
+	arr := mapToArray(a.groups)
+	a.written = time.Now()
 
-	err = os.Rename(tmpfile.Name(), a.output)
-	if err != nil {
-		return err
+	for _, w := range a.writers {
+		if err := w.Write(arr); err != nil {
+			level.Error(log.With(a.logger, "component", "sd-adapter")).Log("err", err)
+		}
 	}
-	return nil
 }
 
 func (a *Adapter) runCustomSD(ctx context.Context) {
+	defer close(a.done)
+
 	updates := a.manager.SyncCh()
 	for {
 		select {
 		case <-ctx.Done():
+			// Flush a delayed write, otherwise the latest changes get lost.
+			if a.delay != nil {
+				a.delay = nil
+				a.writeOutput()
+			}
+
+			return
+		case initial := <-a.initial:
+			// The manager throttles the updates, pass the first one directly
+			// to not wait for the throttle on startup.
+			a.generateTargetGroups(map[string][]*targetgroup.Group{a.name: initial})
 		case allTargetGroups, ok := <-updates:
 			// Handle the case that a target provider exits and closes the channel
 			// before the context is done.
@@ -127,26 +151,84 @@ func (a *Adapter) runCustomSD(ctx context.Context) {
 				return
 			}
 			a.generateTargetGroups(allTargetGroups)
+		case <-a.delay:
+			a.delay = nil
+			a.writeOutput()
 		}
 	}
 }
 
+// Groups returns the current target groups, they could be newer than the
+// written groups if a minimum interval between writes is defined.
+func (a *Adapter) Groups() []writer.Group {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	return mapToArray(a.groups)
+}
+
 // Run starts a Discovery Manager and the custom service discovery implementation.
 func (a *Adapter) Run() {
 	go a.manager.Run()
-	a.manager.StartCustomProvider(a.ctx, a.name, a.disc)
+	a.manager.StartCustomProvider(a.ctx, a.name, &initialDiscoverer{
+		Discoverer: a.disc,
+		initial:    a.initial,
+	})
 	go a.runCustomSD(a.ctx)
 }
 
+// Wait blocks until the adapter has been stopped by the context, a write in
+// progress or a delayed write gets finished before.
+func (a *Adapter) Wait() {
+	<-a.done
+}
+
+// initialDiscoverer passes the first update of the discoverer to the adapter
+// in addition to the manager.
+type initialDiscoverer struct {
+	discovery.Discoverer
+
+	initial chan<- []*targetgroup.Group
+}
+
+// Run implements the discoverer interface.
+func (d *initialDiscoverer) Run(ctx context.Context, up chan<- []*targetgroup.Group) {
+	updates := make(chan []*targetgroup.Group)
+	first := true
+
+	go d.Discoverer.Run(ctx, updates)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case tgs := <-updates:
+			if first {
+				first = false
+				d.initial <- tgs
+			}
+
+			select {
+			case up <- tgs:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
 // NewAdapter creates a new instance of Adapter.
-func NewAdapter(ctx context.Context, file string, name string, d discovery.Discoverer, logger log.Logger) *Adapter {
+func NewAdapter(ctx context.Context, writers []writer.Writer, interval time.Duration, name string, d discovery.Discoverer, logger log.Logger) *Adapter {
 	return &Adapter{
-		ctx:     ctx,
-		disc:    d,
-		groups:  make(map[string]*customSD),
-		manager: discovery.NewManager(ctx, logger),
-		output:  file,
-		name:    name,
-		logger:  logger,
+		ctx:      ctx,
+		disc:     d,
+		groups:   make(map[string]*writer.Group),
+		manager:  discovery.NewManager(ctx, logger),
+		writers:  writers,
+		interval: interval,
+		initial:  make(chan []*targetgroup.Group, 1),
+		name:     name,
+		logger:   logger,
+		done:     make(chan struct{}),
 	}
 }
@@ -2,6 +2,7 @@ package command
 
 import (
 	"os"
+	"strings"
 
 	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
 	"github.com/promhippie/prometheus-hetzner-sd/pkg/version"
@@ -23,8 +24,19 @@ func Run() error {
 			},
 		},
 		Flags: RootFlags(cfg),
+		Before: func(c *cli.Context) error {
+			if c.IsSet("enable-feature") {
+				cfg.Features = c.StringSlice("enable-feature")
+			}
+
+			return nil
+		},
 		Commands: []*cli.Command{
+			Config(cfg),
+			Credentials(cfg),
 			Health(cfg),
+			Inventory(cfg),
+			List(cfg),
 			Server(cfg),
 		},
 	}
@@ -54,12 +66,38 @@ func RootFlags(cfg *config.Config) []cli.Flag {
 			EnvVars:     []string{"PROMETHEUS_HETZNER_LOG_LEVEL"},
 			Destination: &cfg.Logs.Level,
 		},
+		&cli.StringFlag{
+			Name:        "log.format",
+			Value:       "json",
+			Usage:       "Output format of log messages, one of logfmt or json",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_LOG_FORMAT"},
+			Destination: &cfg.Logs.Format,
+		},
 		&cli.BoolFlag{
 			Name:        "log.pretty",
 			Value:       false,
-			Usage:       "Enable pretty messages for logging",
+			Usage:       "Enable pretty messages for logging, overrides the format with logfmt",
 			EnvVars:     []string{"PROMETHEUS_HETZNER_LOG_PRETTY"},
 			Destination: &cfg.Logs.Pretty,
 		},
+		&cli.StringFlag{
+			Name:        "log.output",
+			Value:       "stdout",
+			Usage:       "Destination of log messages, one of stdout, syslog or journal",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_LOG_OUTPUT"},
+			Destination: &cfg.Logs.Output,
+		},
+		&cli.GenericFlag{
+			Name:    "log.dedup",
+			Value:   defaultDuration(&cfg.Logs.Dedup, 0),
+			Usage:   "Interval to suppress repeated warnings and errors, zero disables it",
+			EnvVars: []string{"PROMETHEUS_HETZNER_LOG_DEDUP"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "enable-feature",
+			Value:   cli.NewStringSlice(),
+			Usage:   "Enable experimental features like " + strings.Join(config.FeatureNames(), ", "),
+			EnvVars: []string{"PROMETHEUS_HETZNER_ENABLE_FEATURE"},
+		},
 	}
 }
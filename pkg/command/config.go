@@ -0,0 +1,336 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/go-kit/log/level"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/action"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// Config provides the sub-command to interact with the configuration.
+func Config(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "config",
+		Usage: "Configuration related commands",
+		Subcommands: []*cli.Command{
+			ConfigSchema(cfg),
+			ConfigValidate(cfg),
+			ConfigPrint(cfg),
+			ConfigInit(cfg),
+		},
+	}
+}
+
+// ConfigSchema provides the sub-command to print the configuration schema.
+func ConfigSchema(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "schema",
+		Usage: "Print a JSON Schema of the configuration file",
+		Action: func(c *cli.Context) error {
+			content, err := json.MarshalIndent(config.Schema(), "", "  ")
+
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintln(c.App.Writer, string(content))
+			return nil
+		},
+	}
+}
+
+// ConfigValidate provides the sub-command to validate the configuration.
+func ConfigValidate(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "validate",
+		Usage: "Validate the configuration without contacting the API",
+		Flags: ServerFlags(cfg),
+		Action: func(c *cli.Context) error {
+			if err := setupConfig(c, cfg); err != nil {
+				return err
+			}
+
+			logger := setupLogger(cfg)
+
+			if err := setupHetzner(c, cfg, logger); err != nil {
+				return err
+			}
+
+			if err := setupServer(c, cfg, logger); err != nil {
+				return err
+			}
+
+			return action.Validate(cfg, logger)
+		},
+	}
+}
+
+// ConfigPrint provides the sub-command to print the effective configuration.
+func ConfigPrint(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "print",
+		Usage: "Print the effective configuration with masked secrets",
+		Flags: append([]cli.Flag{
+			&cli.StringFlag{
+				Name:    "format",
+				Aliases: []string{"f"},
+				Value:   "yaml",
+				Usage:   "Output format like yaml or json",
+			},
+		}, ServerFlags(cfg)...),
+		Action: func(c *cli.Context) error {
+			if err := setupConfig(c, cfg); err != nil {
+				return err
+			}
+
+			logger := setupLogger(cfg)
+
+			if err := setupHetzner(c, cfg, logger); err != nil {
+				return err
+			}
+
+			if err := setupServer(c, cfg, logger); err != nil {
+				return err
+			}
+
+			var (
+				content []byte
+				err     error
+			)
+
+			switch c.String("format") {
+			case "yaml":
+				content, err = yaml.Marshal(cfg.Redacted())
+			case "json":
+				buf := &bytes.Buffer{}
+				enc := json.NewEncoder(buf)
+				enc.SetEscapeHTML(false)
+				enc.SetIndent("", "  ")
+
+				err = enc.Encode(cfg.Redacted())
+				content = buf.Bytes()
+			default:
+				level.Error(logger).Log(
+					"msg", "Unsupported value for format",
+					"format", c.String("format"),
+				)
+
+				return errors.New("unsupported value for format")
+			}
+
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintln(c.App.Writer, strings.TrimSpace(string(content)))
+			return nil
+		},
+	}
+}
+
+// ConfigInit provides the sub-command to generate an example configuration.
+func ConfigInit(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "init",
+		Usage: "Generate a commented example configuration",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Value:   "",
+				Usage:   "Path to write the configuration to, defaults to stdout",
+			},
+			&cli.BoolFlag{
+				Name:  "force",
+				Value: false,
+				Usage: "Overwrite the configuration file if it already exists",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			logger := setupLogger(cfg)
+			content, err := sampleConfig()
+
+			if err != nil {
+				level.Error(logger).Log(
+					"msg", "Failed to generate config",
+					"err", err,
+				)
+
+				return err
+			}
+
+			file := c.String("output")
+
+			if file == "" {
+				_, err := c.App.Writer.Write(content)
+				return err
+			}
+
+			if _, err := os.Stat(file); err == nil && !c.Bool("force") {
+				level.Error(logger).Log(
+					"msg", "Config already exists",
+					"file", file,
+				)
+
+				return errors.New("config already exists")
+			}
+
+			if err := ioutil.WriteFile(file, content, 0600); err != nil {
+				level.Error(logger).Log(
+					"msg", "Failed to write config",
+					"file", file,
+					"err", err,
+				)
+
+				return err
+			}
+
+			return nil
+		},
+	}
+}
+
+// sampleConfig renders a configuration with all defaults of the flags, the
+// usage of the flags is attached as comments to the matching settings.
+func sampleConfig() ([]byte, error) {
+	sample := config.Load()
+	set := flag.NewFlagSet("init", flag.ContinueOnError)
+
+	for _, f := range append(RootFlags(sample), ServerFlags(sample)...) {
+		if err := f.Apply(set); err != nil {
+			return nil, err
+		}
+	}
+
+	sample.Target.Credentials = []config.Credential{
+		{
+			Project: "example",
+		},
+	}
+
+	node := &yaml3.Node{}
+
+	if err := node.Encode(sample); err != nil {
+		return nil, err
+	}
+
+	comments := make(map[string]string)
+	fields := make(map[fieldKey]string)
+
+	settingPaths(reflect.ValueOf(sample).Elem(), "", fields)
+
+	for _, f := range append(RootFlags(sample), ServerFlags(sample)...) {
+		usage, target := flagTarget(f)
+
+		if path, ok := fields[target]; ok && target.addr != 0 {
+			comments[path] = usage
+		}
+	}
+
+	attachComments(node, "", comments)
+
+	buf := &bytes.Buffer{}
+	enc := yaml3.NewEncoder(buf)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(node); err != nil {
+		return nil, err
+	}
+
+	return append([]byte("---\n"), buf.Bytes()...), nil
+}
+
+// fieldKey identifies a setting by the address and the type of the field.
+type fieldKey struct {
+	addr uintptr
+	kind reflect.Type
+}
+
+// flagTarget returns the usage and the destination of a flag.
+func flagTarget(f cli.Flag) (string, fieldKey) {
+	var (
+		usage  string
+		target interface{}
+	)
+
+	switch v := f.(type) {
+	case *cli.StringFlag:
+		usage, target = v.Usage, v.Destination
+	case *cli.IntFlag:
+		usage, target = v.Usage, v.Destination
+	case *cli.BoolFlag:
+		usage, target = v.Usage, v.Destination
+	case *cli.Float64Flag:
+		usage, target = v.Usage, v.Destination
+	case *cli.DurationFlag:
+		usage, target = v.Usage, v.Destination
+	case *cli.GenericFlag:
+		usage, target = v.Usage, v.Value
+	}
+
+	value := reflect.ValueOf(target)
+
+	if !value.IsValid() || value.Kind() != reflect.Ptr || value.IsNil() {
+		return usage, fieldKey{}
+	}
+
+	return usage, fieldKey{
+		addr: value.Pointer(),
+		kind: value.Type().Elem(),
+	}
+}
+
+// settingPaths collects the YAML paths of all struct fields by their address.
+func settingPaths(value reflect.Value, prefix string, result map[fieldKey]string) {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+
+		if name == "" || name == "-" {
+			continue
+		}
+
+		path := prefix + name
+		result[fieldKey{
+			addr: value.Field(i).Addr().Pointer(),
+			kind: field.Type,
+		}] = path
+
+		if field.Type.Kind() == reflect.Struct {
+			settingPaths(value.Field(i), path+".", result)
+		}
+	}
+}
+
+// attachComments adds the comments to the keys of the mapping nodes.
+func attachComments(node *yaml3.Node, prefix string, comments map[string]string) {
+	switch node.Kind {
+	case yaml3.DocumentNode:
+		for _, child := range node.Content {
+			attachComments(child, prefix, comments)
+		}
+	case yaml3.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			path := prefix + key.Value
+
+			if comment, ok := comments[path]; ok {
+				key.HeadComment = comment
+			}
+
+			attachComments(value, path+".", comments)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package command
+
+import (
+	"github.com/go-kit/log/level"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/action"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
+	"github.com/urfave/cli/v2"
+)
+
+// Credentials provides the sub-command to interact with the credentials.
+func Credentials(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "credentials",
+		Usage: "Credentials related commands",
+		Subcommands: []*cli.Command{
+			CredentialsTest(cfg),
+		},
+	}
+}
+
+// CredentialsTest provides the sub-command to test the credentials.
+func CredentialsTest(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "test",
+		Usage: "Perform a read-only request per project to test the credentials",
+		Flags: HetznerFlags(cfg),
+		Action: func(c *cli.Context) error {
+			if err := setupConfig(c, cfg); err != nil {
+				return err
+			}
+
+			logger := setupLogger(cfg)
+
+			if err := setupHetzner(c, cfg, logger); err != nil {
+				return err
+			}
+
+			if err := action.TestCredentials(cfg, logger, c.App.Writer); err != nil {
+				level.Error(logger).Log(
+					"msg", "Failed to test credentials",
+					"err", err,
+				)
+
+				return err
+			}
+
+			return nil
+		},
+	}
+}
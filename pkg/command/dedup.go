@@ -0,0 +1,125 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// dedupEntry defines a logged message and the number of suppressed repeats.
+type dedupEntry struct {
+	keyvals    []interface{}
+	logged     time.Time
+	suppressed int
+}
+
+// dedupLogger suppresses repeated warnings and errors within the interval, once
+// the interval has passed the number of suppressed repeats gets logged. The
+// timestamp and the refresh ID are ignored when matching the messages.
+type dedupLogger struct {
+	next     log.Logger
+	interval time.Duration
+	entries  map[string]*dedupEntry
+	mutex    sync.Mutex
+}
+
+func newDedupLogger(next log.Logger, interval time.Duration) log.Logger {
+	return &dedupLogger{
+		next:     next,
+		interval: interval,
+		entries:  make(map[string]*dedupEntry),
+	}
+}
+
+// Log implements the logger interface.
+func (l *dedupLogger) Log(keyvals ...interface{}) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	l.flush(now)
+
+	if !dedupLevel(keyvals) {
+		return l.next.Log(keyvals...)
+	}
+
+	key := dedupKey(keyvals)
+
+	if entry, ok := l.entries[key]; ok {
+		entry.suppressed++
+		return nil
+	}
+
+	l.entries[key] = &dedupEntry{
+		keyvals: keyvals,
+		logged:  now,
+	}
+
+	return l.next.Log(keyvals...)
+}
+
+// flush logs the repeats of all messages with an expired interval.
+func (l *dedupLogger) flush(now time.Time) {
+	for key, entry := range l.entries {
+		if now.Sub(entry.logged) < l.interval {
+			continue
+		}
+
+		delete(l.entries, key)
+
+		if entry.suppressed == 0 {
+			continue
+		}
+
+		keyvals := make([]interface{}, 0, len(entry.keyvals)+4)
+		keyvals = append(keyvals, entry.keyvals...)
+		keyvals = append(keyvals, "repeated", entry.suppressed, "within", l.interval.String())
+
+		l.next.Log(keyvals...)
+	}
+}
+
+// dedupLevel checks if the message is a warning or an error.
+func dedupLevel(keyvals []interface{}) bool {
+	for i := 0; i < len(keyvals)-1; i += 2 {
+		if keyvals[i] != level.Key() {
+			continue
+		}
+
+		switch fmt.Sprint(keyvals[i+1]) {
+		case level.WarnValue().String(), level.ErrorValue().String():
+			return true
+		}
+	}
+
+	return false
+}
+
+var (
+	// dedupIgnored defines the keys which differ for every refresh or log line
+	// and are not part of the identity of a message.
+	dedupIgnored = map[string]struct{}{
+		"ts":      {},
+		"refresh": {},
+	}
+)
+
+// dedupKey builds the identity of a message from all of its values, except
+// the ignored keys like the refresh ID.
+func dedupKey(keyvals []interface{}) string {
+	parts := make([]string, 0, len(keyvals)/2)
+
+	for i := 0; i < len(keyvals)-1; i += 2 {
+		if _, ok := dedupIgnored[fmt.Sprint(keyvals[i])]; ok {
+			continue
+		}
+
+		parts = append(parts, fmt.Sprintf("%v=%v", keyvals[i], keyvals[i+1]))
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -1,12 +1,19 @@
 package command
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
+	"io/ioutil"
+	"net"
 	"net/http"
+	"net/url"
+	"strings"
 
-	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/log/level"
 	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
 	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
 )
 
 // Health provides the sub-command to perform a health check.
@@ -16,26 +23,75 @@ func Health(cfg *config.Config) *cli.Command {
 		Usage: "Perform health checks",
 		Flags: HealthFlags(cfg),
 		Action: func(c *cli.Context) error {
+			if err := setupConfig(c, cfg); err != nil {
+				return err
+			}
+
 			logger := setupLogger(cfg)
 
-			if c.IsSet("hetzner.config") {
-				if err := readConfig(c.String("hetzner.config"), cfg); err != nil {
-					level.Error(logger).Log(
-						"msg", "Failed to read config",
-						"err", err,
-					)
+			scheme, err := healthScheme(cfg.Server.Web)
+
+			if err != nil {
+				level.Error(logger).Log(
+					"msg", "Failed to read web config",
+					"file", cfg.Server.Web,
+					"err", err,
+				)
+
+				return err
+			}
+
+			endpoint := url.URL{
+				Scheme: scheme,
+				Host:   healthAddr(cfg.Server.Addr),
+				Path:   "/healthz",
+			}
+
+			transport := &http.Transport{
+				TLSClientConfig: &tls.Config{
+					// The health check targets the loopback address which
+					// is usually not part of the server certificate.
+					InsecureSkipVerify: true,
+				},
+			}
+
+			if strings.HasPrefix(cfg.Server.Addr, unixPrefix) {
+				path := strings.TrimPrefix(cfg.Server.Addr, unixPrefix)
+				endpoint.Host = "localhost"
 
-					return err
+				transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return (&net.Dialer{}).DialContext(ctx, "unix", path)
 				}
 			}
 
-			resp, err := http.Get(
-				fmt.Sprintf(
-					"http://%s/healthz",
-					cfg.Server.Addr,
-				),
+			client := &http.Client{
+				Transport: transport,
+			}
+
+			req, err := http.NewRequest(
+				http.MethodGet,
+				endpoint.String(),
+				nil,
 			)
 
+			if err != nil {
+				level.Error(logger).Log(
+					"msg", "Failed to prepare health check",
+					"err", err,
+				)
+
+				return err
+			}
+
+			if c.IsSet("health.username") {
+				req.SetBasicAuth(
+					c.String("health.username"),
+					c.String("health.password"),
+				)
+			}
+
+			resp, err := client.Do(req)
+
 			if err != nil {
 				level.Error(logger).Log(
 					"msg", "Failed to request health check",
@@ -50,11 +106,10 @@ func Health(cfg *config.Config) *cli.Command {
 			if resp.StatusCode != 200 {
 				level.Error(logger).Log(
 					"msg", "Health check seems to be in bad state",
-					"err", err,
 					"code", resp.StatusCode,
 				)
 
-				return err
+				return fmt.Errorf("unexpected status code %d", resp.StatusCode)
 			}
 
 			return nil
@@ -68,16 +123,100 @@ func HealthFlags(cfg *config.Config) []cli.Flag {
 		&cli.StringFlag{
 			Name:        "web.address",
 			Value:       "0.0.0.0:9000",
-			Usage:       "Address to bind the metrics server",
+			Usage:       "Address to bind the metrics server, can be a unix:// socket",
 			EnvVars:     []string{"PROMETHEUS_HETZNER_WEB_ADDRESS"},
 			Destination: &cfg.Server.Addr,
 		},
+		&cli.StringFlag{
+			Name:        "web.config.file",
+			Aliases:     []string{"web.config"},
+			Value:       "",
+			Usage:       "Path to web-config file to enable TLS or authentication",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_WEB_CONFIG_FILE", "PROMETHEUS_HETZNER_WEB_CONFIG"},
+			Destination: &cfg.Server.Web,
+		},
+		&cli.StringFlag{
+			Name:    "health.username",
+			Value:   "",
+			Usage:   "Username for basic auth if it's enabled by the web-config file",
+			EnvVars: []string{"PROMETHEUS_HETZNER_HEALTH_USERNAME"},
+		},
+		&cli.StringFlag{
+			Name:    "health.password",
+			Value:   "",
+			Usage:   "Password for basic auth if it's enabled by the web-config file",
+			EnvVars: []string{"PROMETHEUS_HETZNER_HEALTH_PASSWORD"},
+		},
 		&cli.StringFlag{
 			Name:        "hetzner.config",
 			Value:       "",
-			Usage:       "Path to Hetzner configuration file",
+			Usage:       "Path to the configuration file",
 			EnvVars:     []string{"PROMETHEUS_HETZNER_CONFIG"},
 			Destination: nil,
 		},
+		&cli.BoolFlag{
+			Name:        "config.lenient",
+			Value:       false,
+			Usage:       "Ignore unknown keys within the configuration file",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_CONFIG_LENIENT"},
+			Destination: &cfg.Lenient,
+		},
+	}
+}
+
+const (
+	// unixPrefix defines the prefix of addresses for unix domain sockets.
+	unixPrefix = "unix://"
+)
+
+// healthScheme detects if the server is served via TLS based on the web config.
+func healthScheme(file string) (string, error) {
+	if file == "" {
+		return "http", nil
+	}
+
+	content, err := ioutil.ReadFile(file)
+
+	if err != nil {
+		return "", err
+	}
+
+	webConfig := struct {
+		TLSConfig struct {
+			CertFile string `yaml:"cert_file"`
+		} `yaml:"tls_server_config"`
+	}{}
+
+	if err := yaml.Unmarshal(content, &webConfig); err != nil {
+		return "", err
+	}
+
+	if webConfig.TLSConfig.CertFile != "" {
+		return "https", nil
 	}
+
+	return "http", nil
+}
+
+// healthAddr replaces unspecified listen addresses by the matching loopback
+// address, so the health check also works for IPv6-only servers.
+func healthAddr(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+
+	if err != nil {
+		return addr
+	}
+
+	switch ip := net.ParseIP(host); {
+	case host == "":
+		return net.JoinHostPort("127.0.0.1", port)
+	case ip == nil:
+		return addr
+	case ip.IsUnspecified() && ip.To4() != nil:
+		return net.JoinHostPort("127.0.0.1", port)
+	case ip.IsUnspecified():
+		return net.JoinHostPort("::1", port)
+	}
+
+	return addr
 }
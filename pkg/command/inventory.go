@@ -0,0 +1,55 @@
+package command
+
+import (
+	"github.com/go-kit/log/level"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/action"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
+	"github.com/urfave/cli/v2"
+)
+
+// Inventory provides the sub-command to print an Ansible inventory.
+func Inventory(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "inventory",
+		Usage: "Print servers as Ansible dynamic inventory",
+		Flags: InventoryFlags(cfg),
+		Action: func(c *cli.Context) error {
+			if err := setupConfig(c, cfg); err != nil {
+				return err
+			}
+
+			logger := setupLogger(cfg)
+
+			if err := setupHetzner(c, cfg, logger); err != nil {
+				return err
+			}
+
+			if err := action.Inventory(cfg, logger, c.String("host"), c.App.Writer); err != nil {
+				level.Error(logger).Log(
+					"msg", "Failed to render inventory",
+					"err", err,
+				)
+
+				return err
+			}
+
+			return nil
+		},
+	}
+}
+
+// InventoryFlags defines the available inventory flags.
+func InventoryFlags(cfg *config.Config) []cli.Flag {
+	return append([]cli.Flag{
+		&cli.BoolFlag{
+			Name:  "list",
+			Value: true,
+			Usage: "List all groups and hosts, that's the default",
+		},
+		&cli.StringFlag{
+			Name:  "host",
+			Value: "",
+			Usage: "Only print the variables of a single host",
+		},
+	}, HetznerFlags(cfg)...)
+}
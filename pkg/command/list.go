@@ -0,0 +1,66 @@
+package command
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/go-kit/log/level"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/action"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
+	"github.com/urfave/cli/v2"
+)
+
+// List provides the sub-command to print the discovered servers.
+func List(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "list",
+		Usage: "Print discovered servers with their labels",
+		Flags: ListFlags(cfg),
+		Action: func(c *cli.Context) error {
+			if err := setupConfig(c, cfg); err != nil {
+				return err
+			}
+
+			logger := setupLogger(cfg)
+
+			switch strings.ToLower(c.String("format")) {
+			case "table", "json", "csv":
+				break
+			default:
+				level.Error(logger).Log(
+					"msg", "Invalid value for format",
+					"format", c.String("format"),
+				)
+
+				return errors.New("invalid value for format")
+			}
+
+			if err := setupHetzner(c, cfg, logger); err != nil {
+				return err
+			}
+
+			if err := action.List(cfg, logger, c.String("format"), c.App.Writer); err != nil {
+				level.Error(logger).Log(
+					"msg", "Failed to list servers",
+					"err", err,
+				)
+
+				return err
+			}
+
+			return nil
+		},
+	}
+}
+
+// ListFlags defines the available list flags.
+func ListFlags(cfg *config.Config) []cli.Flag {
+	return append([]cli.Flag{
+		&cli.StringFlag{
+			Name:    "format",
+			Aliases: []string{"f"},
+			Value:   "table",
+			Usage:   "Output format, can be table, json or csv",
+		},
+	}, HetznerFlags(cfg)...)
+}
@@ -0,0 +1,106 @@
+//go:build !windows
+// +build !windows
+
+package command
+
+import (
+	"fmt"
+	"io"
+	gosyslog "log/syslog"
+	"net"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/syslog"
+)
+
+const (
+	// journalSocket defines the socket of the native journal protocol.
+	journalSocket = "/run/systemd/journal/socket"
+)
+
+// systemLogger creates a logger writing to syslog or the systemd journal, the
+// levels get mapped to the matching priorities.
+func systemLogger(output, identifier string, format func(io.Writer) log.Logger) (log.Logger, error) {
+	switch output {
+	case "syslog":
+		w, err := gosyslog.New(gosyslog.LOG_DAEMON|gosyslog.LOG_INFO, identifier)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return syslog.NewSyslogLogger(w, format), nil
+	case "journal":
+		conn, err := net.Dial("unixgram", journalSocket)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return syslog.NewSyslogLogger(&journalWriter{
+			conn:       conn,
+			identifier: identifier,
+		}, format), nil
+	}
+
+	return nil, fmt.Errorf("unsupported log output %s", output)
+}
+
+// journalWriter sends the messages to the systemd journal with the native
+// protocol, so the priorities are kept without any syslog daemon.
+type journalWriter struct {
+	conn       net.Conn
+	identifier string
+}
+
+func (w *journalWriter) send(priority gosyslog.Priority, msg string) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "PRIORITY=%d\n", priority)
+	fmt.Fprintf(&b, "SYSLOG_IDENTIFIER=%s\n", w.identifier)
+	fmt.Fprintf(&b, "MESSAGE=%s\n", strings.ReplaceAll(strings.TrimSpace(msg), "\n", " "))
+
+	_, err := w.conn.Write([]byte(b.String()))
+	return err
+}
+
+func (w *journalWriter) Write(p []byte) (int, error) {
+	return len(p), w.send(gosyslog.LOG_INFO, string(p))
+}
+
+func (w *journalWriter) Close() error {
+	return w.conn.Close()
+}
+
+func (w *journalWriter) Emerg(msg string) error {
+	return w.send(gosyslog.LOG_EMERG, msg)
+}
+
+func (w *journalWriter) Alert(msg string) error {
+	return w.send(gosyslog.LOG_ALERT, msg)
+}
+
+func (w *journalWriter) Crit(msg string) error {
+	return w.send(gosyslog.LOG_CRIT, msg)
+}
+
+func (w *journalWriter) Err(msg string) error {
+	return w.send(gosyslog.LOG_ERR, msg)
+}
+
+func (w *journalWriter) Warning(msg string) error {
+	return w.send(gosyslog.LOG_WARNING, msg)
+}
+
+func (w *journalWriter) Notice(msg string) error {
+	return w.send(gosyslog.LOG_NOTICE, msg)
+}
+
+func (w *journalWriter) Info(msg string) error {
+	return w.send(gosyslog.LOG_INFO, msg)
+}
+
+func (w *journalWriter) Debug(msg string) error {
+	return w.send(gosyslog.LOG_DEBUG, msg)
+}
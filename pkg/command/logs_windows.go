@@ -0,0 +1,17 @@
+//go:build windows
+// +build windows
+
+package command
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/go-kit/log"
+)
+
+// systemLogger is not supported on windows, there is neither syslog nor the
+// systemd journal available.
+func systemLogger(output, identifier string, format func(io.Writer) log.Logger) (log.Logger, error) {
+	return nil, fmt.Errorf("unsupported log output %s on windows", output)
+}
@@ -2,11 +2,25 @@ package command
 
 import (
 	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+	"time"
 
-	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/exporter-toolkit/web"
 	"github.com/promhippie/prometheus-hetzner-sd/pkg/action"
 	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/writer"
 	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// minRefresh defines the lowest allowed refresh interval.
+	minRefresh = time.Second
 )
 
 // Server provides the sub-command to start the server.
@@ -16,79 +30,444 @@ func Server(cfg *config.Config) *cli.Command {
 		Usage: "Start integrated server",
 		Flags: ServerFlags(cfg),
 		Action: func(c *cli.Context) error {
+			if err := setupConfig(c, cfg); err != nil {
+				return err
+			}
+
 			logger := setupLogger(cfg)
 
-			if c.IsSet("hetzner.config") {
-				if err := readConfig(c.String("hetzner.config"), cfg); err != nil {
-					level.Error(logger).Log(
-						"msg", "Failed to read config",
-						"err", err,
-					)
+			if err := setupHetzner(c, cfg, logger); err != nil {
+				return err
+			}
 
-					return err
-				}
+			if err := setupServer(c, cfg, logger); err != nil {
+				return err
 			}
 
-			if cfg.Target.File == "" {
-				level.Error(logger).Log(
-					"msg", "Missing path for output.file",
-				)
+			return action.Server(cfg, logger)
+		},
+	}
+}
 
-				return errors.New("missing path for output.file")
-			}
+// setupServer applies and validates the settings of the server command.
+func setupServer(c *cli.Context, cfg *config.Config, logger log.Logger) error {
+	if c.IsSet("output.zookeeper.servers") {
+		cfg.Target.Zookeeper.Servers = c.StringSlice("output.zookeeper.servers")
+	}
 
-			if c.IsSet("hetzner.username") && c.IsSet("hetzner.password") {
-				credentials := config.Credential{
-					Project:  "default",
-					Username: c.String("hetzner.username"),
-					Password: c.String("hetzner.password"),
-				}
+	if c.IsSet("web.cors.origins") {
+		cfg.Server.CORS.Origins = c.StringSlice("web.cors.origins")
+	}
 
-				cfg.Target.Credentials = append(
-					cfg.Target.Credentials,
-					credentials,
-				)
+	if c.IsSet("web.cors.methods") {
+		cfg.Server.CORS.Methods = c.StringSlice("web.cors.methods")
+	}
 
-				if credentials.Username == "" {
-					level.Error(logger).Log(
-						"msg", "Missing required hetzner.username",
-					)
+	if c.IsSet("web.allow") {
+		cfg.Server.Allow = c.StringSlice("web.allow")
+	}
 
-					return errors.New("missing required hetzner.username")
-				}
+	for _, allow := range cfg.Server.Allow {
+		if _, _, err := net.ParseCIDR(allow); err != nil {
+			level.Error(logger).Log(
+				"msg", "Invalid CIDR for web.allow",
+				"cidr", allow,
+				"err", err,
+			)
 
-				if credentials.Password == "" {
-					level.Error(logger).Log(
-						"msg", "Missing required hetzner.password",
-					)
+			return err
+		}
+	}
 
-					return errors.New("missing required hetzner.password")
-				}
-			}
+	for _, token := range c.StringSlice("web.sd.tokens") {
+		cfg.Server.Tokens = append(cfg.Server.Tokens, config.Token{
+			Token: token,
+		})
+	}
+
+	for i, token := range cfg.Server.Tokens {
+		if value, err := token.Secret(); err != nil || value == "" {
+			level.Error(logger).Log(
+				"msg", "Invalid bearer token for the service discovery",
+				"index", i,
+				"err", err,
+			)
 
-			if len(cfg.Target.Credentials) == 0 {
+			return fmt.Errorf("invalid bearer token at index %d", i)
+		}
+	}
+
+	if c.IsSet("output.labels.include") {
+		cfg.Target.Labels.Include = c.StringSlice("output.labels.include")
+	}
+
+	if c.IsSet("output.labels.exclude") {
+		cfg.Target.Labels.Exclude = c.StringSlice("output.labels.exclude")
+	}
+
+	if c.IsSet("output.vars") {
+		cfg.Target.Vars = make(map[string]string)
+
+		for _, val := range c.StringSlice("output.vars") {
+			parts := strings.SplitN(val, "=", 2)
+
+			if len(parts) != 2 {
 				level.Error(logger).Log(
-					"msg", "Missing any credentials",
+					"msg", "Invalid format for output.vars",
+					"var", val,
 				)
 
-				return errors.New("missing any credentials")
+				return errors.New("invalid format for output.vars")
 			}
 
-			return action.Server(cfg, logger)
-		},
+			cfg.Target.Vars[parts[0]] = parts[1]
+		}
+	}
+
+	if cfg.Server.Web != "" {
+		if err := web.Validate(cfg.Server.Web); err != nil {
+			level.Error(logger).Log(
+				"msg", "Invalid web config",
+				"file", cfg.Server.Web,
+				"err", err,
+			)
+
+			return err
+		}
+	}
+
+	if cfg.Server.Debug && len(cfg.Server.Tokens) == 0 && !basicAuth(cfg.Server.Web) {
+		level.Error(logger).Log(
+			"msg", "Endpoint for target groups requires authentication",
+		)
+
+		return errors.New("web.debug-targets requires bearer tokens or basic auth within the web config")
+	}
+
+	if cfg.Target.Refresh.Duration() < minRefresh {
+		level.Error(logger).Log(
+			"msg", "Value for output.refresh is too low",
+			"refresh", cfg.Target.Refresh,
+			"min", minRefresh,
+		)
+
+		return fmt.Errorf("output.refresh must be at least %s", minRefresh)
+	}
+
+	if cfg.Target.Jitter < 0 || cfg.Target.Jitter > 1 {
+		level.Error(logger).Log(
+			"msg", "Value for output.refresh-jitter is out of range",
+			"jitter", cfg.Target.Jitter,
+		)
+
+		return errors.New("output.refresh-jitter must be between 0 and 1")
+	}
+
+	for _, credential := range cfg.Target.Credentials {
+		if credential.Refresh != 0 && credential.Refresh.Duration() < minRefresh {
+			level.Error(logger).Log(
+				"msg", "Value for the project refresh is too low",
+				"project", credential.Project,
+				"refresh", credential.Refresh,
+				"min", minRefresh,
+			)
+
+			return fmt.Errorf("refresh of project %s must be at least %s", credential.Project, minRefresh)
+		}
+	}
+
+	switch cfg.Target.Hostname {
+	case "", "name", "rdns":
+	default:
+		level.Error(logger).Log(
+			"msg", "Unsupported value for output.hostname",
+			"hostname", cfg.Target.Hostname,
+		)
+
+		return errors.New("unsupported value for output.hostname")
+	}
+
+	if cfg.Target.Retry.Multiplier < 1 {
+		level.Error(logger).Log(
+			"msg", "Value for hetzner.retry.multiplier is too low",
+			"multiplier", cfg.Target.Retry.Multiplier,
+		)
+
+		return errors.New("retry multiplier must be at least 1")
+	}
+
+	if (cfg.Target.TLS.CertFile == "") != (cfg.Target.TLS.KeyFile == "") {
+		level.Error(logger).Log(
+			"msg", "Both hetzner.tls.cert-file and hetzner.tls.key-file are required",
+		)
+
+		return errors.New("client certificate requires cert and key file")
+	}
+
+	if cfg.Target.TLS.Insecure {
+		level.Warn(logger).Log(
+			"msg", "Verification of the API certificate is disabled",
+		)
+	}
+
+	for i, o := range cfg.Target.AllOutputs() {
+		if err := validateOutput(cfg, o, i == 0, logger); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Target.DNS.Addr != "" {
+		if err := requireFeature(cfg, "dns-server", logger); err != nil {
+			return err
+		}
+
+		if cfg.Target.DNS.Domain == "" {
+			level.Error(logger).Log(
+				"msg", "Missing domain for output.dns.domain",
+			)
+
+			return errors.New("missing domain for output.dns.domain")
+		}
+	}
+
+	if cfg.Target.Nats.URL != "" {
+		if err := requireFeature(cfg, "nats-output", logger); err != nil {
+			return err
+		}
+
+		if cfg.Target.Nats.Subject == "" {
+			level.Error(logger).Log(
+				"msg", "Missing subject for output.nats.subject",
+			)
+
+			return errors.New("missing subject for output.nats.subject")
+		}
+	}
+
+	return nil
+}
+
+// basicAuth checks if the web config defines any users for basic auth.
+func basicAuth(file string) bool {
+	if file == "" {
+		return false
+	}
+
+	content, err := ioutil.ReadFile(file)
+
+	if err != nil {
+		return false
+	}
+
+	webConfig := struct {
+		Users map[string]string `yaml:"basic_auth_users"`
+	}{}
+
+	if err := yaml.Unmarshal(content, &webConfig); err != nil {
+		return false
+	}
+
+	return len(webConfig.Users) > 0
+}
+
+// defaultDuration assigns the default to the duration and returns it to be
+// used as the value of a generic flag.
+func defaultDuration(d *config.Duration, value time.Duration) *config.Duration {
+	*d = config.Duration(value)
+	return d
+}
+
+func validateOutput(cfg *config.Config, o config.Output, primary bool, logger log.Logger) error {
+	switch o.Split {
+	case "", "datacenter", "location":
+	default:
+		level.Error(logger).Log(
+			"msg", "Unsupported value for output.split",
+			"split", o.Split,
+		)
+
+		return errors.New("unsupported value for output.split")
+	}
+
+	switch o.GroupBy {
+	case "", "server", "datacenter", "project", "none":
+	default:
+		level.Error(logger).Log(
+			"msg", "Unsupported value for output.group-by",
+			"group", o.GroupBy,
+		)
+
+		return errors.New("unsupported value for output.group-by")
 	}
+
+	if o.Indent < 0 {
+		level.Error(logger).Log(
+			"msg", "Invalid value for output.indent",
+			"indent", o.Indent,
+		)
+
+		return errors.New("invalid value for output.indent")
+	}
+
+	switch o.Engine {
+	case "file", "http":
+		if o.File == "" {
+			level.Error(logger).Log(
+				"msg", "Missing path for output.file",
+			)
+
+			return errors.New("missing path for output.file")
+		}
+
+		if o.Engine == "http" && !primary {
+			level.Error(logger).Log(
+				"msg", "The http engine is only supported by the primary output",
+			)
+
+			return errors.New("the http engine is only supported by the primary output")
+		}
+
+		if o.Engine == "http" && (o.Split != "" || writer.IsTemplate(o.File)) {
+			level.Error(logger).Log(
+				"msg", "Splitting the output is not supported by the http engine",
+			)
+
+			return errors.New("splitting the output is not supported by the http engine")
+		}
+	case "zookeeper":
+		if len(o.Zookeeper.Servers) == 0 {
+			level.Error(logger).Log(
+				"msg", "Missing servers for output.zookeeper.servers",
+			)
+
+			return errors.New("missing servers for output.zookeeper.servers")
+		}
+
+		if o.Zookeeper.Path == "" {
+			level.Error(logger).Log(
+				"msg", "Missing path for output.zookeeper.path",
+			)
+
+			return errors.New("missing path for output.zookeeper.path")
+		}
+	case "kubernetes":
+		if o.Kubernetes.Name == "" {
+			level.Error(logger).Log(
+				"msg", "Missing name for output.kubernetes.name",
+			)
+
+			return errors.New("missing name for output.kubernetes.name")
+		}
+
+		if o.Kubernetes.Key == "" && o.Kubernetes.Kind != "scrapeconfig" {
+			level.Error(logger).Log(
+				"msg", "Missing key for output.kubernetes.key",
+			)
+
+			return errors.New("missing key for output.kubernetes.key")
+		}
+	case "s3":
+		if o.S3.Bucket == "" {
+			level.Error(logger).Log(
+				"msg", "Missing bucket for output.s3.bucket",
+			)
+
+			return errors.New("missing bucket for output.s3.bucket")
+		}
+
+		if o.S3.Key == "" {
+			level.Error(logger).Log(
+				"msg", "Missing key for output.s3.key",
+			)
+
+			return errors.New("missing key for output.s3.key")
+		}
+	case "redis":
+		if err := requireFeature(cfg, "redis-output", logger); err != nil {
+			return err
+		}
+
+		if o.Redis.Addr == "" {
+			level.Error(logger).Log(
+				"msg", "Missing address for output.redis.addr",
+			)
+
+			return errors.New("missing address for output.redis.addr")
+		}
+
+		if o.Redis.Key == "" {
+			level.Error(logger).Log(
+				"msg", "Missing key for output.redis.key",
+			)
+
+			return errors.New("missing key for output.redis.key")
+		}
+	default:
+		level.Error(logger).Log(
+			"msg", "Unsupported engine for output.engine",
+			"engine", o.Engine,
+		)
+
+		return errors.New("unsupported engine for output.engine")
+	}
+
+	return nil
 }
 
 // ServerFlags defines the available server flags.
 func ServerFlags(cfg *config.Config) []cli.Flag {
-	return []cli.Flag{
+	return append([]cli.Flag{
 		&cli.StringFlag{
 			Name:        "web.address",
 			Value:       "0.0.0.0:9000",
-			Usage:       "Address to bind the metrics server",
+			Usage:       "Address to bind the metrics server, can be a unix:// socket",
 			EnvVars:     []string{"PROMETHEUS_HETZNER_WEB_ADDRESS"},
 			Destination: &cfg.Server.Addr,
 		},
+		&cli.BoolFlag{
+			Name:        "web.systemd-socket",
+			Value:       false,
+			Usage:       "Use the socket passed by the systemd socket activation instead of the address",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_WEB_SYSTEMD_SOCKET"},
+			Destination: &cfg.Server.Systemd,
+		},
+		&cli.GenericFlag{
+			Name:    "web.shutdown-timeout",
+			Value:   defaultDuration(&cfg.Server.Shutdown, 5*time.Second),
+			Usage:   "Timeout to drain the connections of the web server on shutdown",
+			EnvVars: []string{"PROMETHEUS_HETZNER_WEB_SHUTDOWN_TIMEOUT"},
+		},
+		&cli.BoolFlag{
+			Name:        "web.access-log",
+			Value:       false,
+			Usage:       "Log all requests handled by the web server",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_WEB_ACCESS_LOG"},
+			Destination: &cfg.Server.AccessLog,
+		},
+		&cli.StringSliceFlag{
+			Name:    "web.cors.origins",
+			Value:   cli.NewStringSlice(),
+			Usage:   "List of origins allowed for cross-origin requests, * allows any origin",
+			EnvVars: []string{"PROMETHEUS_HETZNER_WEB_CORS_ORIGINS"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "web.cors.methods",
+			Value:   cli.NewStringSlice(),
+			Usage:   "List of methods allowed for cross-origin requests, defaults to GET and HEAD",
+			EnvVars: []string{"PROMETHEUS_HETZNER_WEB_CORS_METHODS"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "web.allow",
+			Value:   cli.NewStringSlice(),
+			Usage:   "List of CIDRs allowed to connect to the web server, all others get rejected",
+			EnvVars: []string{"PROMETHEUS_HETZNER_WEB_ALLOW"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "web.sd.tokens",
+			Value:   cli.NewStringSlice(),
+			Usage:   "List of bearer tokens granting access to the service discovery of all projects",
+			EnvVars: []string{"PROMETHEUS_HETZNER_WEB_SD_TOKENS"},
+		},
 		&cli.StringFlag{
 			Name:        "web.path",
 			Value:       "/metrics",
@@ -97,33 +476,474 @@ func ServerFlags(cfg *config.Config) []cli.Flag {
 			Destination: &cfg.Server.Path,
 		},
 		&cli.StringFlag{
-			Name:        "web.config",
+			Name:        "web.config.file",
+			Aliases:     []string{"web.config"},
 			Value:       "",
-			Usage:       "Path to web-config file",
-			EnvVars:     []string{"PROMETHEUS_HETZNER_WEB_CONFIG"},
+			Usage:       "Path to web-config file to enable TLS or authentication",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_WEB_CONFIG_FILE", "PROMETHEUS_HETZNER_WEB_CONFIG"},
 			Destination: &cfg.Server.Web,
 		},
+		&cli.BoolFlag{
+			Name:        "web.enable-lifecycle",
+			Value:       false,
+			Usage:       "Enable the endpoint to reload the configuration and to trigger a refresh",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_WEB_ENABLE_LIFECYCLE"},
+			Destination: &cfg.Server.Lifecycle,
+		},
+		&cli.BoolFlag{
+			Name:        "web.debug-targets",
+			Value:       false,
+			Usage:       "Enable the endpoint for the current target groups, requires bearer tokens or basic auth",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_WEB_DEBUG_TARGETS"},
+			Destination: &cfg.Server.Debug,
+		},
+		&cli.BoolFlag{
+			Name:        "web.pprof",
+			Value:       false,
+			Usage:       "Enable the pprof endpoints for profiling",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_WEB_PPROF"},
+			Destination: &cfg.Server.Pprof,
+		},
+		&cli.StringFlag{
+			Name:        "web.pprof.address",
+			Value:       "",
+			Usage:       "Address to bind a separate pprof server, defaults to the metrics server",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_WEB_PPROF_ADDRESS"},
+			Destination: &cfg.Server.PprofAddr,
+		},
+		&cli.StringFlag{
+			Name:        "tracing.endpoint",
+			Value:       "",
+			Usage:       "Endpoint like localhost:4318 to export traces via OTLP, tracing is disabled if empty",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_TRACING_ENDPOINT"},
+			Destination: &cfg.Tracing.Endpoint,
+		},
+		&cli.BoolFlag{
+			Name:        "tracing.insecure",
+			Value:       false,
+			Usage:       "Export traces via plain HTTP instead of HTTPS",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_TRACING_INSECURE"},
+			Destination: &cfg.Tracing.Insecure,
+		},
+		&cli.Float64Flag{
+			Name:        "tracing.sample-ratio",
+			Value:       1.0,
+			Usage:       "Ratio of refreshes getting traced between 0 and 1",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_TRACING_SAMPLE_RATIO"},
+			Destination: &cfg.Tracing.Ratio,
+		},
+		&cli.StringFlag{
+			Name:        "audit.file",
+			Value:       "",
+			Usage:       "Path to append the changes of the targets as JSON lines, disabled if empty",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_AUDIT_FILE"},
+			Destination: &cfg.Audit.File,
+		},
+		&cli.IntFlag{
+			Name:        "audit.max-size",
+			Value:       10,
+			Usage:       "Maximum size of the audit log in megabytes before it gets rotated, zero disables the rotation",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_AUDIT_MAX_SIZE"},
+			Destination: &cfg.Audit.MaxSize,
+		},
+		&cli.IntFlag{
+			Name:        "audit.max-backups",
+			Value:       5,
+			Usage:       "Number of rotated audit logs to keep",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_AUDIT_MAX_BACKUPS"},
+			Destination: &cfg.Audit.MaxBackups,
+		},
 		&cli.StringFlag{
 			Name:        "output.engine",
 			Value:       "file",
-			Usage:       "Enabled engine like file or http",
+			Usage:       "Enabled engine like file, http, zookeeper, kubernetes, s3 or redis",
 			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_ENGINE"},
 			Destination: &cfg.Target.Engine,
 		},
 		&cli.StringFlag{
 			Name:        "output.file",
 			Value:       "/etc/prometheus/hetzner.json",
-			Usage:       "Path to write the file_sd config",
+			Usage:       "Path to write the file_sd config, can be a template",
 			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_FILE"},
 			Destination: &cfg.Target.File,
 		},
+		&cli.StringFlag{
+			Name:        "output.state-file",
+			Value:       "",
+			Usage:       "Path to persist the last discovered servers to serve them after a restart, disabled if empty",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_STATE_FILE"},
+			Destination: &cfg.Target.State,
+		},
+		&cli.GenericFlag{
+			Name:    "output.refresh",
+			Value:   defaultDuration(&cfg.Target.Refresh, 30*time.Second),
+			Usage:   "Discovery refresh interval as duration like 5m or in seconds",
+			EnvVars: []string{"PROMETHEUS_HETZNER_OUTPUT_REFRESH"},
+		},
+		&cli.Float64Flag{
+			Name:        "output.refresh-jitter",
+			Value:       0,
+			Usage:       "Share of the refresh interval between 0 and 1 randomly added to every refresh",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_REFRESH_JITTER"},
+			Destination: &cfg.Target.Jitter,
+		},
+		&cli.GenericFlag{
+			Name:    "output.refresh-stagger",
+			Value:   defaultDuration(&cfg.Target.Stagger, 0),
+			Usage:   "Maximum random delay of the first refresh per project on startup, zero disables it",
+			EnvVars: []string{"PROMETHEUS_HETZNER_OUTPUT_REFRESH_STAGGER"},
+		},
+		&cli.GenericFlag{
+			Name:    "output.interval",
+			Value:   defaultDuration(&cfg.Target.Interval, 0),
+			Usage:   "Minimum interval between output writes as duration like 10s or in seconds",
+			EnvVars: []string{"PROMETHEUS_HETZNER_OUTPUT_INTERVAL"},
+		},
+		&cli.IntFlag{
+			Name:        "output.max-targets",
+			Value:       0,
+			Usage:       "Refuse to write if more targets get discovered, zero disables it",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_MAX_TARGETS"},
+			Destination: &cfg.Target.MaxTargets,
+		},
+		&cli.StringFlag{
+			Name:        "output.split",
+			Value:       "",
+			Usage:       "Split the output file per datacenter or location",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_SPLIT"},
+			Destination: &cfg.Target.Split,
+		},
+		&cli.IntFlag{
+			Name:        "output.port",
+			Value:       0,
+			Usage:       "Port appended to the addresses of the targets",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_PORT"},
+			Destination: &cfg.Target.Port,
+		},
+		&cli.StringFlag{
+			Name:        "output.address",
+			Value:       "",
+			Usage:       "Template for the addresses of the targets, e.g. {{ .IPv4 }}:9100",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_ADDRESS"},
+			Destination: &cfg.Target.Address,
+		},
+		&cli.StringFlag{
+			Name:        "output.hostname",
+			Value:       "",
+			Usage:       "Use the server name or the reverse DNS as target by name or rdns",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_HOSTNAME"},
+			Destination: &cfg.Target.Hostname,
+		},
+		&cli.StringFlag{
+			Name:        "output.domain",
+			Value:       "",
+			Usage:       "Domain suffix appended to the server names",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_DOMAIN"},
+			Destination: &cfg.Target.Domain,
+		},
 		&cli.IntFlag{
-			Name:        "output.refresh",
+			Name:        "output.indent",
+			Value:       4,
+			Usage:       "Number of spaces used to indent the output file",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_INDENT"},
+			Destination: &cfg.Target.Indent,
+		},
+		&cli.BoolFlag{
+			Name:        "output.compact",
+			Value:       false,
+			Usage:       "Write the output file as compact JSON without indentation",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_COMPACT"},
+			Destination: &cfg.Target.Compact,
+		},
+		&cli.StringFlag{
+			Name:        "output.group-by",
+			Value:       "server",
+			Usage:       "Bundle targets into groups per server, datacenter, project or none",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_GROUP_BY"},
+			Destination: &cfg.Target.GroupBy,
+		},
+		&cli.StringSliceFlag{
+			Name:    "output.labels.include",
+			Value:   cli.NewStringSlice(),
+			Usage:   "List of globs or /regex/ patterns for labels to keep within the output",
+			EnvVars: []string{"PROMETHEUS_HETZNER_OUTPUT_LABELS_INCLUDE"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "output.labels.exclude",
+			Value:   cli.NewStringSlice(),
+			Usage:   "List of globs or /regex/ patterns for labels to drop from the output",
+			EnvVars: []string{"PROMETHEUS_HETZNER_OUTPUT_LABELS_EXCLUDE"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "output.vars",
+			Value:   cli.NewStringSlice(),
+			Usage:   "List of key=value variables available within templated file names",
+			EnvVars: []string{"PROMETHEUS_HETZNER_OUTPUT_VARS"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "output.zookeeper.servers",
+			Value:   cli.NewStringSlice(),
+			Usage:   "List of ZooKeeper servers for the zookeeper engine",
+			EnvVars: []string{"PROMETHEUS_HETZNER_OUTPUT_ZOOKEEPER_SERVERS"},
+		},
+		&cli.StringFlag{
+			Name:        "output.zookeeper.path",
+			Value:       "/prometheus/hetzner",
+			Usage:       "Path of the serverset znode for the zookeeper engine",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_ZOOKEEPER_PATH"},
+			Destination: &cfg.Target.Zookeeper.Path,
+		},
+		&cli.IntFlag{
+			Name:        "output.zookeeper.port",
+			Value:       9100,
+			Usage:       "Endpoint port for serverset members without a port",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_ZOOKEEPER_PORT"},
+			Destination: &cfg.Target.Zookeeper.Port,
+		},
+		&cli.IntFlag{
+			Name:        "output.zookeeper.timeout",
+			Value:       10,
+			Usage:       "Session timeout for ZooKeeper in seconds",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_ZOOKEEPER_TIMEOUT"},
+			Destination: &cfg.Target.Zookeeper.Timeout,
+		},
+		&cli.StringFlag{
+			Name:        "output.kubernetes.namespace",
+			Value:       "",
+			Usage:       "Namespace for the kubernetes engine, defaults to the pod namespace",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_KUBERNETES_NAMESPACE"},
+			Destination: &cfg.Target.Kubernetes.Namespace,
+		},
+		&cli.StringFlag{
+			Name:        "output.kubernetes.name",
+			Value:       "prometheus-hetzner-sd",
+			Usage:       "Name of the resource for the kubernetes engine",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_KUBERNETES_NAME"},
+			Destination: &cfg.Target.Kubernetes.Name,
+		},
+		&cli.StringFlag{
+			Name:        "output.kubernetes.key",
+			Value:       "hetzner.json",
+			Usage:       "Key within the ConfigMap or Secret for the kubernetes engine",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_KUBERNETES_KEY"},
+			Destination: &cfg.Target.Kubernetes.Key,
+		},
+		&cli.StringFlag{
+			Name:        "output.kubernetes.kind",
+			Value:       "configmap",
+			Usage:       "Kind of resource for the kubernetes engine like configmap, secret or scrapeconfig",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_KUBERNETES_KIND"},
+			Destination: &cfg.Target.Kubernetes.Kind,
+		},
+		&cli.StringFlag{
+			Name:        "output.s3.endpoint",
+			Value:       "",
+			Usage:       "Endpoint for S3-compatible storages for the s3 engine",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_S3_ENDPOINT"},
+			Destination: &cfg.Target.S3.Endpoint,
+		},
+		&cli.StringFlag{
+			Name:        "output.s3.region",
+			Value:       "us-east-1",
+			Usage:       "Region of the bucket for the s3 engine",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_S3_REGION"},
+			Destination: &cfg.Target.S3.Region,
+		},
+		&cli.StringFlag{
+			Name:        "output.s3.bucket",
+			Value:       "",
+			Usage:       "Name of the bucket for the s3 engine",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_S3_BUCKET"},
+			Destination: &cfg.Target.S3.Bucket,
+		},
+		&cli.StringFlag{
+			Name:        "output.s3.key",
+			Value:       "hetzner.json",
+			Usage:       "Object key within the bucket for the s3 engine",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_S3_KEY"},
+			Destination: &cfg.Target.S3.Key,
+		},
+		&cli.StringFlag{
+			Name:        "output.s3.access-key",
+			Value:       "",
+			Usage:       "Access key for the s3 engine, defaults to the AWS credential chain",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_S3_ACCESS_KEY"},
+			Destination: &cfg.Target.S3.AccessKey,
+		},
+		&cli.StringFlag{
+			Name:        "output.s3.secret-key",
+			Value:       "",
+			Usage:       "Secret key for the s3 engine, defaults to the AWS credential chain",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_S3_SECRET_KEY"},
+			Destination: &cfg.Target.S3.SecretKey,
+		},
+		&cli.BoolFlag{
+			Name:        "output.s3.path-style",
+			Value:       false,
+			Usage:       "Use path-style addressing for the s3 engine",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_S3_PATH_STYLE"},
+			Destination: &cfg.Target.S3.PathStyle,
+		},
+		&cli.IntFlag{
+			Name:        "output.s3.timeout",
+			Value:       10,
+			Usage:       "Timeout for uploads of the s3 engine in seconds",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_S3_TIMEOUT"},
+			Destination: &cfg.Target.S3.Timeout,
+		},
+		&cli.StringFlag{
+			Name:        "output.redis.addr",
+			Value:       "",
+			Usage:       "Address of the Redis server for the redis engine",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_REDIS_ADDR"},
+			Destination: &cfg.Target.Redis.Addr,
+		},
+		&cli.StringFlag{
+			Name:        "output.redis.password",
+			Value:       "",
+			Usage:       "Password of the Redis server for the redis engine",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_REDIS_PASSWORD"},
+			Destination: &cfg.Target.Redis.Password,
+		},
+		&cli.IntFlag{
+			Name:        "output.redis.db",
+			Value:       0,
+			Usage:       "Database of the Redis server for the redis engine",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_REDIS_DB"},
+			Destination: &cfg.Target.Redis.DB,
+		},
+		&cli.StringFlag{
+			Name:        "output.redis.key",
+			Value:       "prometheus:hetzner",
+			Usage:       "Key to store the targets for the redis engine",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_REDIS_KEY"},
+			Destination: &cfg.Target.Redis.Key,
+		},
+		&cli.StringFlag{
+			Name:        "output.redis.channel",
+			Value:       "",
+			Usage:       "Channel to publish the targets to on changes for the redis engine",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_REDIS_CHANNEL"},
+			Destination: &cfg.Target.Redis.Channel,
+		},
+		&cli.IntFlag{
+			Name:        "output.redis.timeout",
+			Value:       10,
+			Usage:       "Timeout for Redis commands in seconds",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_REDIS_TIMEOUT"},
+			Destination: &cfg.Target.Redis.Timeout,
+		},
+		&cli.StringFlag{
+			Name:        "output.dns.addr",
+			Value:       "",
+			Usage:       "Address to bind the embedded DNS server",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_DNS_ADDR"},
+			Destination: &cfg.Target.DNS.Addr,
+		},
+		&cli.StringFlag{
+			Name:        "output.dns.domain",
+			Value:       "hetzner.sd.local",
+			Usage:       "Domain served by the embedded DNS server",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_DNS_DOMAIN"},
+			Destination: &cfg.Target.DNS.Domain,
+		},
+		&cli.StringFlag{
+			Name:        "output.dns.service",
+			Value:       "node",
+			Usage:       "Service name used for the SRV records",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_DNS_SERVICE"},
+			Destination: &cfg.Target.DNS.Service,
+		},
+		&cli.IntFlag{
+			Name:        "output.dns.port",
+			Value:       9100,
+			Usage:       "Port of the SRV records for targets without a port",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_DNS_PORT"},
+			Destination: &cfg.Target.DNS.Port,
+		},
+		&cli.IntFlag{
+			Name:        "output.dns.ttl",
 			Value:       30,
-			Usage:       "Discovery refresh interval in seconds",
-			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_REFRESH"},
-			Destination: &cfg.Target.Refresh,
+			Usage:       "TTL of the DNS records in seconds",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_DNS_TTL"},
+			Destination: &cfg.Target.DNS.TTL,
+		},
+		&cli.StringFlag{
+			Name:        "output.webhook.url",
+			Value:       "",
+			Usage:       "URL to post target changes to",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_WEBHOOK_URL"},
+			Destination: &cfg.Target.Webhook.URL,
+		},
+		&cli.StringFlag{
+			Name:        "output.webhook.secret",
+			Value:       "",
+			Usage:       "Secret to sign the webhook payload with HMAC-SHA256",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_WEBHOOK_SECRET"},
+			Destination: &cfg.Target.Webhook.Secret,
+		},
+		&cli.IntFlag{
+			Name:        "output.webhook.timeout",
+			Value:       10,
+			Usage:       "Timeout for webhook requests in seconds",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_WEBHOOK_TIMEOUT"},
+			Destination: &cfg.Target.Webhook.Timeout,
+		},
+		&cli.StringFlag{
+			Name:        "output.nats.url",
+			Value:       "",
+			Usage:       "URL of the NATS server to publish target changes to",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_NATS_URL"},
+			Destination: &cfg.Target.Nats.URL,
 		},
+		&cli.StringFlag{
+			Name:        "output.nats.subject",
+			Value:       "prometheus.hetzner.targets",
+			Usage:       "Subject to publish target changes to",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_NATS_SUBJECT"},
+			Destination: &cfg.Target.Nats.Subject,
+		},
+		&cli.IntFlag{
+			Name:        "output.nats.timeout",
+			Value:       10,
+			Usage:       "Timeout for NATS connections and publishing in seconds",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_NATS_TIMEOUT"},
+			Destination: &cfg.Target.Nats.Timeout,
+		},
+		&cli.BoolFlag{
+			Name:        "output.blackbox",
+			Value:       false,
+			Usage:       "Format the targets for probing by the blackbox exporter",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_BLACKBOX"},
+			Destination: &cfg.Target.Blackbox.Enabled,
+		},
+		&cli.StringFlag{
+			Name:        "output.blackbox.target",
+			Value:       "{{ .Address }}",
+			Usage:       "Template for the probed target of the blackbox exporter",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_BLACKBOX_TARGET"},
+			Destination: &cfg.Target.Blackbox.Target,
+		},
+		&cli.StringFlag{
+			Name:        "output.blackbox.module",
+			Value:       "",
+			Usage:       "Module used by the blackbox exporter to probe the targets",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_BLACKBOX_MODULE"},
+			Destination: &cfg.Target.Blackbox.Module,
+		},
+		&cli.StringFlag{
+			Name:        "output.blackbox.exporter",
+			Value:       "",
+			Usage:       "Address of the blackbox exporter used as scrape address",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_OUTPUT_BLACKBOX_EXPORTER"},
+			Destination: &cfg.Target.Blackbox.Exporter,
+		},
+	}, HetznerFlags(cfg)...)
+}
+
+// HetznerFlags defines the available flags for the Hetzner API.
+func HetznerFlags(cfg *config.Config) []cli.Flag {
+	return []cli.Flag{
 		&cli.StringFlag{
 			Name:    "hetzner.username",
 			Value:   "",
@@ -136,11 +956,284 @@ func ServerFlags(cfg *config.Config) []cli.Flag {
 			Usage:   "Password for the Hetzner API",
 			EnvVars: []string{"PROMETHEUS_HETZNER_PASSWORD"},
 		},
+		&cli.StringFlag{
+			Name:    "hetzner.username-file",
+			Value:   "",
+			Usage:   "Path to a file containing the username for the Hetzner API",
+			EnvVars: []string{"PROMETHEUS_HETZNER_USERNAME_FILE"},
+		},
+		&cli.StringFlag{
+			Name:    "hetzner.password-file",
+			Value:   "",
+			Usage:   "Path to a file containing the password for the Hetzner API",
+			EnvVars: []string{"PROMETHEUS_HETZNER_PASSWORD_FILE"},
+		},
+		&cli.StringFlag{
+			Name:    "hetzner.keyring",
+			Value:   "",
+			Usage:   "Service name to look up the password within the keyring of the operating system",
+			EnvVars: []string{"PROMETHEUS_HETZNER_KEYRING"},
+		},
+		&cli.BoolFlag{
+			Name:        "hetzner.subnets",
+			Value:       false,
+			Usage:       "Emit targets for all usable addresses of assigned subnets",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_SUBNETS"},
+			Destination: &cfg.Target.Subnets.Enabled,
+		},
+		&cli.StringSliceFlag{
+			Name:    "hetzner.subnets.exclude",
+			Value:   cli.NewStringSlice(),
+			Usage:   "List of CIDRs to exclude from subnet targets",
+			EnvVars: []string{"PROMETHEUS_HETZNER_SUBNETS_EXCLUDE"},
+		},
+		&cli.IntFlag{
+			Name:        "hetzner.budget",
+			Value:       200,
+			Usage:       "Number of requests allowed per project within the budget interval, zero disables the tracking",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_BUDGET"},
+			Destination: &cfg.Target.Budget.Requests,
+		},
+		&cli.GenericFlag{
+			Name:    "hetzner.budget.interval",
+			Value:   defaultDuration(&cfg.Target.Budget.Interval, time.Hour),
+			Usage:   "Interval of the request budget as duration like 1h or in seconds",
+			EnvVars: []string{"PROMETHEUS_HETZNER_BUDGET_INTERVAL"},
+		},
+		&cli.StringFlag{
+			Name:        "hetzner.endpoint",
+			Value:       "https://robot-ws.your-server.de",
+			Usage:       "Base URL of the Robot API, e.g. a mock server or an API gateway",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_ENDPOINT"},
+			Destination: &cfg.Target.Endpoint,
+		},
+		&cli.IntFlag{
+			Name:        "hetzner.retry.attempts",
+			Value:       3,
+			Usage:       "Maximum number of attempts per request to the API, one disables the retries",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_RETRY_ATTEMPTS"},
+			Destination: &cfg.Target.Retry.Attempts,
+		},
+		&cli.GenericFlag{
+			Name:    "hetzner.retry.initial",
+			Value:   defaultDuration(&cfg.Target.Retry.Initial, 500*time.Millisecond),
+			Usage:   "Initial backoff between the attempts as duration like 500ms or in seconds",
+			EnvVars: []string{"PROMETHEUS_HETZNER_RETRY_INITIAL"},
+		},
+		&cli.Float64Flag{
+			Name:        "hetzner.retry.multiplier",
+			Value:       2.0,
+			Usage:       "Multiplier for the backoff after every failed attempt",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_RETRY_MULTIPLIER"},
+			Destination: &cfg.Target.Retry.Multiplier,
+		},
+		&cli.GenericFlag{
+			Name:    "hetzner.retry.max",
+			Value:   defaultDuration(&cfg.Target.Retry.Max, 5*time.Second),
+			Usage:   "Maximum backoff between the attempts as duration like 5s or in seconds",
+			EnvVars: []string{"PROMETHEUS_HETZNER_RETRY_MAX"},
+		},
+		&cli.GenericFlag{
+			Name:    "hetzner.timeout",
+			Value:   defaultDuration(&cfg.Target.Timeouts.Request, 15*time.Second),
+			Usage:   "Overall timeout of a single request to the API, zero disables the timeout",
+			EnvVars: []string{"PROMETHEUS_HETZNER_TIMEOUT"},
+		},
+		&cli.GenericFlag{
+			Name:    "hetzner.timeout.connect",
+			Value:   defaultDuration(&cfg.Target.Timeouts.Connect, 5*time.Second),
+			Usage:   "Timeout to establish a connection to the API, zero disables the timeout",
+			EnvVars: []string{"PROMETHEUS_HETZNER_TIMEOUT_CONNECT"},
+		},
+		&cli.GenericFlag{
+			Name:    "hetzner.timeout.read",
+			Value:   defaultDuration(&cfg.Target.Timeouts.Read, 10*time.Second),
+			Usage:   "Timeout to wait for the response headers of the API after sending a request, zero disables the timeout",
+			EnvVars: []string{"PROMETHEUS_HETZNER_TIMEOUT_READ"},
+		},
+		&cli.StringFlag{
+			Name:        "hetzner.proxy-url",
+			Value:       "",
+			Usage:       "URL of a proxy for requests to the API, defaults to the proxy environment variables",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_PROXY_URL"},
+			Destination: &cfg.Target.Proxy.URL,
+		},
+		&cli.StringFlag{
+			Name:        "hetzner.proxy-username",
+			Value:       "",
+			Usage:       "Username for the authentication at the proxy",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_PROXY_USERNAME"},
+			Destination: &cfg.Target.Proxy.Username,
+		},
+		&cli.StringFlag{
+			Name:        "hetzner.proxy-password",
+			Value:       "",
+			Usage:       "Password for the authentication at the proxy",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_PROXY_PASSWORD"},
+			Destination: &cfg.Target.Proxy.Password,
+		},
+		&cli.StringFlag{
+			Name:        "hetzner.tls.ca-file",
+			Value:       "",
+			Usage:       "Path to a CA bundle to verify the certificate of the API, defaults to the system pool",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_TLS_CA_FILE"},
+			Destination: &cfg.Target.TLS.CAFile,
+		},
+		&cli.StringFlag{
+			Name:        "hetzner.tls.cert-file",
+			Value:       "",
+			Usage:       "Path to a client certificate for requests to the API",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_TLS_CERT_FILE"},
+			Destination: &cfg.Target.TLS.CertFile,
+		},
+		&cli.StringFlag{
+			Name:        "hetzner.tls.key-file",
+			Value:       "",
+			Usage:       "Path to the key of the client certificate",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_TLS_KEY_FILE"},
+			Destination: &cfg.Target.TLS.KeyFile,
+		},
+		&cli.BoolFlag{
+			Name:        "hetzner.tls.insecure-skip-verify",
+			Value:       false,
+			Usage:       "Skip the verification of the certificate of the API, only use it for testing",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_TLS_INSECURE_SKIP_VERIFY"},
+			Destination: &cfg.Target.TLS.Insecure,
+		},
+		&cli.IntFlag{
+			Name:        "hetzner.transport.max-idle-conns",
+			Value:       100,
+			Usage:       "Maximum number of idle connections to the API, zero means no limit",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_TRANSPORT_MAX_IDLE_CONNS"},
+			Destination: &cfg.Target.Transport.MaxIdle,
+		},
+		&cli.IntFlag{
+			Name:        "hetzner.transport.max-idle-conns-per-host",
+			Value:       10,
+			Usage:       "Maximum number of idle connections kept per host of the API",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_TRANSPORT_MAX_IDLE_CONNS_PER_HOST"},
+			Destination: &cfg.Target.Transport.MaxIdlePerHost,
+		},
+		&cli.GenericFlag{
+			Name:    "hetzner.transport.idle-timeout",
+			Value:   defaultDuration(&cfg.Target.Transport.IdleTimeout, 90*time.Second),
+			Usage:   "Duration an idle connection to the API is kept open, zero keeps it forever",
+			EnvVars: []string{"PROMETHEUS_HETZNER_TRANSPORT_IDLE_TIMEOUT"},
+		},
+		&cli.BoolFlag{
+			Name:        "hetzner.transport.http2",
+			Value:       true,
+			Usage:       "Attempt to use HTTP/2 for requests to the API",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_TRANSPORT_HTTP2"},
+			Destination: &cfg.Target.Transport.HTTP2,
+		},
+		&cli.GenericFlag{
+			Name:    "hetzner.stale-after",
+			Value:   defaultDuration(&cfg.Target.StaleAfter, 0),
+			Usage:   "Grace period after a missed refresh until the previous targets of a project are marked as stale, zero disables the marking",
+			EnvVars: []string{"PROMETHEUS_HETZNER_STALE_AFTER"},
+		},
+		&cli.IntFlag{
+			Name:        "hetzner.workers",
+			Value:       4,
+			Usage:       "Number of projects fetched concurrently, zero fetches all projects at once",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_WORKERS"},
+			Destination: &cfg.Target.Workers,
+		},
+		&cli.GenericFlag{
+			Name:    "hetzner.project-timeout",
+			Value:   defaultDuration(&cfg.Target.Timeout, time.Minute),
+			Usage:   "Timeout for fetching a single project including all retries, zero disables the timeout",
+			EnvVars: []string{"PROMETHEUS_HETZNER_PROJECT_TIMEOUT"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "hetzner.datacenter",
+			Value:   cli.NewStringSlice(),
+			Usage:   "List of datacenters or locations to filter the servers",
+			EnvVars: []string{"PROMETHEUS_HETZNER_DATACENTER"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "hetzner.products.include",
+			Value:   cli.NewStringSlice(),
+			Usage:   "List of globs or /regex/ patterns for products to include",
+			EnvVars: []string{"PROMETHEUS_HETZNER_PRODUCTS_INCLUDE"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "hetzner.products.exclude",
+			Value:   cli.NewStringSlice(),
+			Usage:   "List of globs or /regex/ patterns for products to exclude",
+			EnvVars: []string{"PROMETHEUS_HETZNER_PRODUCTS_EXCLUDE"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "hetzner.names.include",
+			Value:   cli.NewStringSlice(),
+			Usage:   "List of globs or /regex/ patterns for server names to include",
+			EnvVars: []string{"PROMETHEUS_HETZNER_NAMES_INCLUDE"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "hetzner.names.exclude",
+			Value:   cli.NewStringSlice(),
+			Usage:   "List of globs or /regex/ patterns for server names to exclude",
+			EnvVars: []string{"PROMETHEUS_HETZNER_NAMES_EXCLUDE"},
+		},
+		&cli.IntSliceFlag{
+			Name:    "hetzner.numbers.allow",
+			Value:   cli.NewIntSlice(),
+			Usage:   "List of server numbers to allow, all others are dropped",
+			EnvVars: []string{"PROMETHEUS_HETZNER_NUMBERS_ALLOW"},
+		},
+		&cli.IntSliceFlag{
+			Name:    "hetzner.numbers.deny",
+			Value:   cli.NewIntSlice(),
+			Usage:   "List of server numbers to deny",
+			EnvVars: []string{"PROMETHEUS_HETZNER_NUMBERS_DENY"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "hetzner.status",
+			Value:   cli.NewStringSlice(),
+			Usage:   "List of allowed server statuses like ready",
+			EnvVars: []string{"PROMETHEUS_HETZNER_STATUS"},
+		},
+		&cli.BoolFlag{
+			Name:        "hetzner.exclude-cancelled",
+			Value:       false,
+			Usage:       "Exclude servers which have been cancelled",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_EXCLUDE_CANCELLED"},
+			Destination: &cfg.Target.Filters.Cancelled.Exclude,
+		},
+		&cli.IntFlag{
+			Name:        "hetzner.exclude-cancelled.grace",
+			Value:       0,
+			Usage:       "Keep cancelled servers until the given days before the cancellation date",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_EXCLUDE_CANCELLED_GRACE"},
+			Destination: &cfg.Target.Filters.Cancelled.Grace,
+		},
+		&cli.StringFlag{
+			Name:        "hetzner.label-prefix",
+			Value:       "hetzner_",
+			Usage:       "Prefix of the labels after __meta_, can be empty to remove it",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_LABEL_PREFIX"},
+			Destination: &cfg.Target.Prefix,
+		},
 		&cli.StringFlag{
 			Name:    "hetzner.config",
 			Value:   "",
-			Usage:   "Path to Hetzner configuration file",
+			Usage:   "Path to the configuration file",
 			EnvVars: []string{"PROMETHEUS_HETZNER_CONFIG"},
 		},
+		&cli.BoolFlag{
+			Name:        "config.lenient",
+			Value:       false,
+			Usage:       "Ignore unknown keys within the configuration file",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_CONFIG_LENIENT"},
+			Destination: &cfg.Lenient,
+		},
+		&cli.BoolFlag{
+			Name:        "hetzner.config.watch",
+			Value:       false,
+			Usage:       "Reload the credentials if the configuration or secret files change",
+			EnvVars:     []string{"PROMETHEUS_HETZNER_CONFIG_WATCH"},
+			Destination: &cfg.Watch,
+		},
 	}
 }
@@ -1,37 +1,49 @@
 package command
 
 import (
-	"encoding/json"
 	"errors"
-	"io/ioutil"
+	"fmt"
+	"net"
 	"os"
-	"path/filepath"
 	"strings"
 
-	"github.com/go-kit/kit/log"
-	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/common/model"
 	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
-	"gopkg.in/yaml.v2"
+	"github.com/urfave/cli/v2"
 )
 
 var (
 	// ErrConfigFormatInvalid defines the error if ext is unsupported.
-	ErrConfigFormatInvalid = errors.New("config extension is not supported")
+	ErrConfigFormatInvalid = config.ErrFormatInvalid
 )
 
 func setupLogger(cfg *config.Config) log.Logger {
-	var logger log.Logger
+	var (
+		logger log.Logger
+		err    error
+	)
 
-	if cfg.Logs.Pretty {
-		logger = log.NewSyncLogger(
-			log.NewLogfmtLogger(os.Stdout),
-		)
-	} else {
-		logger = log.NewSyncLogger(
-			log.NewJSONLogger(os.Stdout),
-		)
+	format := log.NewJSONLogger
+
+	if cfg.Logs.Pretty || strings.ToLower(cfg.Logs.Format) == "logfmt" {
+		format = log.NewLogfmtLogger
+	}
+
+	switch output := strings.ToLower(cfg.Logs.Output); output {
+	case "", "stdout":
+		logger = format(os.Stdout)
+	default:
+		logger, err = systemLogger(output, "prometheus-hetzner-sd", format)
+
+		if err != nil {
+			logger = format(os.Stdout)
+		}
 	}
 
+	logger = log.NewSyncLogger(logger)
+
 	switch strings.ToLower(cfg.Logs.Level) {
 	case "error":
 		logger = level.NewFilter(logger, level.AllowError())
@@ -45,35 +57,304 @@ func setupLogger(cfg *config.Config) log.Logger {
 		logger = level.NewFilter(logger, level.AllowInfo())
 	}
 
-	return log.With(
+	logger = log.With(
 		logger,
 		"ts", log.DefaultTimestampUTC,
 	)
+
+	if cfg.Logs.Dedup > 0 {
+		logger = newDedupLogger(logger, cfg.Logs.Dedup.Duration())
+	}
+
+	if err != nil {
+		level.Error(logger).Log(
+			"msg", "Failed to initialize log output",
+			"output", cfg.Logs.Output,
+			"err", err,
+		)
+	}
+
+	return logger
 }
 
-func readConfig(file string, cfg *config.Config) error {
-	if file == "" {
+// setupConfig reads the configuration file and applies all explicitly set
+// flags and environment variables on top of it afterwards, so the precedence
+// is flags, environment variables, configuration file and defaults.
+func setupConfig(c *cli.Context, cfg *config.Config) error {
+	if !c.IsSet("hetzner.config") {
 		return nil
 	}
 
-	content, err := ioutil.ReadFile(file)
+	overrides := explicitFlags(c)
+	cfg.File = c.String("hetzner.config")
+
+	if err := config.Read(cfg.File, cfg, !cfg.Lenient); err != nil {
+		level.Error(setupLogger(cfg)).Log(
+			"msg", "Failed to read config",
+			"err", err,
+		)
 
-	if err != nil {
 		return err
 	}
 
-	switch strings.ToLower(filepath.Ext(file)) {
-	case ".yaml", ".yml":
-		if err = yaml.Unmarshal(content, cfg); err != nil {
+	for _, override := range overrides {
+		if err := override.ctx.Set(override.name, override.value); err != nil {
+			level.Error(setupLogger(cfg)).Log(
+				"msg", "Failed to apply flag",
+				"flag", override.name,
+				"err", err,
+			)
+
 			return err
 		}
-	case ".json":
-		if err = json.Unmarshal(content, cfg); err != nil {
+	}
+
+	if c.IsSet("enable-feature") {
+		cfg.Features = c.StringSlice("enable-feature")
+	}
+
+	return nil
+}
+
+// flagOverride defines the value of an explicitly set flag.
+type flagOverride struct {
+	ctx   *cli.Context
+	name  string
+	value string
+}
+
+// explicitFlags collects the current values of all explicitly set flags of
+// the command and the app. Slice flags are skipped as they are always applied
+// after reading the configuration file.
+func explicitFlags(c *cli.Context) []flagOverride {
+	result := make([]flagOverride, 0)
+
+	for i, ctx := range c.Lineage() {
+		var flags []cli.Flag
+
+		switch i {
+		case 0:
+			flags = c.Command.Flags
+		case 1:
+			flags = c.App.Flags
+		}
+
+		for _, f := range flags {
+			switch f.(type) {
+			case *cli.StringSliceFlag, *cli.IntSliceFlag:
+				continue
+			}
+
+			name := f.Names()[0]
+
+			if !ctx.IsSet(name) {
+				continue
+			}
+
+			result = append(result, flagOverride{
+				ctx:   ctx,
+				name:  name,
+				value: fmt.Sprint(ctx.Value(name)),
+			})
+		}
+	}
+
+	return result
+}
+
+func setupHetzner(c *cli.Context, cfg *config.Config, logger log.Logger) error {
+	if err := cfg.ValidateFeatures(); err != nil {
+		level.Error(logger).Log(
+			"msg", "Invalid value for enable-feature",
+			"err", err,
+		)
+
+		return err
+	}
+
+	for _, feature := range cfg.StableFeatures() {
+		level.Warn(logger).Log(
+			"msg", "Feature is always enabled, remove it from enable-feature",
+			"feature", feature,
+		)
+	}
+
+	if !model.LabelName(model.MetaLabelPrefix + cfg.Target.Prefix + "name").IsValid() {
+		level.Error(logger).Log(
+			"msg", "Invalid value for hetzner.label-prefix",
+			"prefix", cfg.Target.Prefix,
+		)
+
+		return errors.New("invalid value for hetzner.label-prefix")
+	}
+
+	if c.IsSet("hetzner.subnets.exclude") {
+		cfg.Target.Subnets.Exclude = c.StringSlice("hetzner.subnets.exclude")
+	}
+
+	for _, exclude := range cfg.Target.Subnets.Exclude {
+		if _, _, err := net.ParseCIDR(exclude); err != nil {
+			level.Error(logger).Log(
+				"msg", "Invalid CIDR for hetzner.subnets.exclude",
+				"cidr", exclude,
+				"err", err,
+			)
+
 			return err
 		}
-	default:
-		return ErrConfigFormatInvalid
+	}
+
+	if c.IsSet("hetzner.datacenter") {
+		cfg.Target.Filters.Datacenters = c.StringSlice("hetzner.datacenter")
+	}
+
+	if c.IsSet("hetzner.products.include") {
+		cfg.Target.Filters.Products.Include = c.StringSlice("hetzner.products.include")
+	}
+
+	if c.IsSet("hetzner.products.exclude") {
+		cfg.Target.Filters.Products.Exclude = c.StringSlice("hetzner.products.exclude")
+	}
+
+	if c.IsSet("hetzner.names.include") {
+		cfg.Target.Filters.Names.Include = c.StringSlice("hetzner.names.include")
+	}
+
+	if c.IsSet("hetzner.names.exclude") {
+		cfg.Target.Filters.Names.Exclude = c.StringSlice("hetzner.names.exclude")
+	}
+
+	if c.IsSet("hetzner.numbers.allow") {
+		cfg.Target.Filters.Numbers.Allow = c.IntSlice("hetzner.numbers.allow")
+	}
+
+	if c.IsSet("hetzner.numbers.deny") {
+		cfg.Target.Filters.Numbers.Deny = c.IntSlice("hetzner.numbers.deny")
+	}
+
+	if c.IsSet("hetzner.status") {
+		cfg.Target.Filters.Statuses = c.StringSlice("hetzner.status")
+	}
+
+	username := c.IsSet("hetzner.username") || c.IsSet("hetzner.username-file")
+	password := c.IsSet("hetzner.password") || c.IsSet("hetzner.password-file") || c.IsSet("hetzner.keyring")
+
+	if username && password {
+		credentials := config.Credential{
+			Project:      "default",
+			Username:     c.String("hetzner.username"),
+			UsernameFile: c.String("hetzner.username-file"),
+			Password:     c.String("hetzner.password"),
+			PasswordFile: c.String("hetzner.password-file"),
+			Keyring:      c.String("hetzner.keyring"),
+		}
+
+		cfg.Target.Credentials = append(
+			cfg.Target.Credentials,
+			credentials,
+		)
+
+		cfg.Defaults = append(
+			cfg.Defaults,
+			credentials,
+		)
+
+		if credentials.Username == "" && credentials.UsernameFile == "" {
+			level.Error(logger).Log(
+				"msg", "Missing required hetzner.username",
+			)
+
+			return errors.New("missing required hetzner.username")
+		}
+
+		if credentials.Password == "" && credentials.PasswordFile == "" && credentials.Keyring == "" {
+			level.Error(logger).Log(
+				"msg", "Missing required hetzner.password",
+			)
+
+			return errors.New("missing required hetzner.password")
+		}
+	}
+
+	indexed, err := envCredentials()
+
+	if err != nil {
+		level.Error(logger).Log(
+			"msg", "Invalid project environment variables",
+			"err", err,
+		)
+
+		return err
+	}
+
+	cfg.Target.Credentials = append(
+		cfg.Target.Credentials,
+		indexed...,
+	)
+
+	cfg.Defaults = append(
+		cfg.Defaults,
+		indexed...,
+	)
+
+	if len(cfg.Target.Credentials) == 0 {
+		level.Error(logger).Log(
+			"msg", "Missing any credentials",
+		)
+
+		return errors.New("missing any credentials")
 	}
 
 	return nil
 }
+
+// envCredentials reads the projects defined by indexed environment variables
+// like PROMETHEUS_HETZNER_PROJECT_0_NAME, starting at zero until the first
+// missing index.
+func envCredentials() ([]config.Credential, error) {
+	result := make([]config.Credential, 0)
+
+	for i := 0; ; i++ {
+		prefix := fmt.Sprintf("PROMETHEUS_HETZNER_PROJECT_%d_", i)
+
+		credential := config.Credential{
+			Project:      os.Getenv(prefix + "NAME"),
+			Username:     os.Getenv(prefix + "USERNAME"),
+			UsernameFile: os.Getenv(prefix + "USERNAME_FILE"),
+			Password:     os.Getenv(prefix + "PASSWORD"),
+			PasswordFile: os.Getenv(prefix + "PASSWORD_FILE"),
+			Keyring:      os.Getenv(prefix + "KEYRING"),
+		}
+
+		if credential.Project == "" && credential.Username == "" && credential.UsernameFile == "" {
+			return result, nil
+		}
+
+		if credential.Project == "" {
+			return nil, fmt.Errorf("missing %sNAME", prefix)
+		}
+
+		if credential.Username == "" && credential.UsernameFile == "" {
+			return nil, fmt.Errorf("missing %sUSERNAME", prefix)
+		}
+
+		if credential.Password == "" && credential.PasswordFile == "" && credential.Keyring == "" {
+			return nil, fmt.Errorf("missing %sPASSWORD", prefix)
+		}
+
+		result = append(result, credential)
+	}
+}
+
+func requireFeature(cfg *config.Config, feature string, logger log.Logger) error {
+	if cfg.Enabled(feature) {
+		return nil
+	}
+
+	level.Error(logger).Log(
+		"msg", "Experimental feature is not enabled",
+		"feature", feature,
+	)
+
+	return fmt.Errorf("experimental feature %s is not enabled", feature)
+}
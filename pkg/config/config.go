@@ -1,38 +1,370 @@
 package config
 
+import (
+	"fmt"
+
+	"github.com/zalando/go-keyring"
+)
+
 // Credential defines a single project credential.
 type Credential struct {
-	Project  string `json:"project" yaml:"project"`
-	Username string `json:"username" yaml:"username"`
-	Password string `json:"password" yaml:"password"`
+	Project      string            `json:"project" yaml:"project"`
+	Username     string            `json:"username" yaml:"username"`
+	UsernameFile string            `json:"username_file" yaml:"username_file"`
+	Password     string            `json:"password" yaml:"password"`
+	PasswordFile string            `json:"password_file" yaml:"password_file"`
+	Keyring      string            `json:"keyring" yaml:"keyring"`
+	Refresh      Duration          `json:"refresh" yaml:"refresh"`
+	Names        Patterns          `json:"names" yaml:"names"`
+	Labels       map[string]string `json:"labels" yaml:"labels"`
+}
+
+// Secrets returns the username and password of the credential, values defined
+// by files are read on every call to pick up rotated secrets. If a keyring
+// service is defined and no password is set the password is looked up within
+// the keyring of the operating system.
+func (c Credential) Secrets() (string, string, error) {
+	username, err := secret(c.Username, c.UsernameFile)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	if c.Keyring != "" && c.Password == "" && c.PasswordFile == "" {
+		password, err := keyring.Get(c.Keyring, username)
+
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read keyring: %w", err)
+		}
+
+		return username, password, nil
+	}
+
+	password, err := secret(c.Password, c.PasswordFile)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	return username, password, nil
+}
+
+// Files returns all files referenced by the credential.
+func (c Credential) Files() []string {
+	result := make([]string, 0, 2)
+
+	for _, file := range []string{c.UsernameFile, c.PasswordFile} {
+		if file != "" {
+			result = append(result, file)
+		}
+	}
+
+	return result
 }
 
 // Server defines the general server configuration.
 type Server struct {
-	Addr string `json:"addr" yaml:"addr"`
-	Path string `json:"path" yaml:"path"`
-	Web  string `json:"web_config" yaml:"web_config"`
+	Addr      string   `json:"addr" yaml:"addr"`
+	Path      string   `json:"path" yaml:"path"`
+	Web       string   `json:"web_config" yaml:"web_config"`
+	Lifecycle bool     `json:"enable_lifecycle" yaml:"enable_lifecycle"`
+	Debug     bool     `json:"debug_targets" yaml:"debug_targets"`
+	Pprof     bool     `json:"pprof" yaml:"pprof"`
+	PprofAddr string   `json:"pprof_addr" yaml:"pprof_addr"`
+	Systemd   bool     `json:"systemd_socket" yaml:"systemd_socket"`
+	Shutdown  Duration `json:"shutdown_timeout" yaml:"shutdown_timeout"`
+	AccessLog bool     `json:"access_log" yaml:"access_log"`
+	CORS      CORS     `json:"cors" yaml:"cors"`
+	Allow     []string `json:"allow" yaml:"allow"`
+	Tokens    []Token  `json:"tokens" yaml:"tokens"`
 }
 
-// Logs defines the level and color for log configuration.
+// Token defines a bearer token for the HTTP service discovery, it grants
+// access to the listed projects or to all projects if none are listed.
+type Token struct {
+	Token     string   `json:"token" yaml:"token"`
+	TokenFile string   `json:"token_file" yaml:"token_file"`
+	Projects  []string `json:"projects" yaml:"projects"`
+}
+
+// Secret returns the value of the token, a token defined by a file is read on
+// every call to pick up rotated tokens.
+func (t Token) Secret() (string, error) {
+	return secret(t.Token, t.TokenFile)
+}
+
+// CORS defines the allowed origins and methods for cross-origin requests.
+type CORS struct {
+	Origins []string `json:"origins" yaml:"origins"`
+	Methods []string `json:"methods" yaml:"methods"`
+}
+
+// Audit defines the audit log of the changes of the targets.
+type Audit struct {
+	File       string `json:"file" yaml:"file"`
+	MaxSize    int    `json:"max_size" yaml:"max_size"`
+	MaxBackups int    `json:"max_backups" yaml:"max_backups"`
+}
+
+// Tracing defines the export of traces via OTLP.
+type Tracing struct {
+	Endpoint string  `json:"endpoint" yaml:"endpoint"`
+	Insecure bool    `json:"insecure" yaml:"insecure"`
+	Ratio    float64 `json:"sample_ratio" yaml:"sample_ratio"`
+}
+
+// Logs defines the level, format and color for log configuration.
 type Logs struct {
-	Level  string `json:"level" yaml:"level"`
-	Pretty bool   `json:"pretty" yaml:"pretty"`
+	Level  string   `json:"level" yaml:"level"`
+	Format string   `json:"format" yaml:"format"`
+	Pretty bool     `json:"pretty" yaml:"pretty"`
+	Output string   `json:"output" yaml:"output"`
+	Dedup  Duration `json:"dedup" yaml:"dedup"`
+}
+
+// Zookeeper defines the configuration for the zookeeper engine.
+type Zookeeper struct {
+	Servers []string `json:"servers" yaml:"servers"`
+	Path    string   `json:"path" yaml:"path"`
+	Port    int      `json:"port" yaml:"port"`
+	Timeout int      `json:"timeout" yaml:"timeout"`
+}
+
+// Kubernetes defines the configuration for the kubernetes engine.
+type Kubernetes struct {
+	Namespace string `json:"namespace" yaml:"namespace"`
+	Name      string `json:"name" yaml:"name"`
+	Key       string `json:"key" yaml:"key"`
+	Kind      string `json:"kind" yaml:"kind"`
+}
+
+// S3 defines the configuration for the s3 engine.
+type S3 struct {
+	Endpoint  string `json:"endpoint" yaml:"endpoint"`
+	Region    string `json:"region" yaml:"region"`
+	Bucket    string `json:"bucket" yaml:"bucket"`
+	Key       string `json:"key" yaml:"key"`
+	AccessKey string `json:"access_key" yaml:"access_key"`
+	SecretKey string `json:"secret_key" yaml:"secret_key"`
+	PathStyle bool   `json:"path_style" yaml:"path_style"`
+	Timeout   int    `json:"timeout" yaml:"timeout"`
+}
+
+// Redis defines the configuration for the Redis output.
+type Redis struct {
+	Addr     string `json:"addr" yaml:"addr"`
+	Password string `json:"password" yaml:"password"`
+	DB       int    `json:"db" yaml:"db"`
+	Key      string `json:"key" yaml:"key"`
+	Channel  string `json:"channel" yaml:"channel"`
+	Timeout  int    `json:"timeout" yaml:"timeout"`
+}
+
+// DNS defines the configuration for the embedded DNS server.
+type DNS struct {
+	Addr    string `json:"addr" yaml:"addr"`
+	Domain  string `json:"domain" yaml:"domain"`
+	Service string `json:"service" yaml:"service"`
+	Port    int    `json:"port" yaml:"port"`
+	TTL     int    `json:"ttl" yaml:"ttl"`
+}
+
+// Webhook defines the configuration for the webhook notifications.
+type Webhook struct {
+	URL     string `json:"url" yaml:"url"`
+	Secret  string `json:"secret" yaml:"secret"`
+	Timeout int    `json:"timeout" yaml:"timeout"`
+}
+
+// Nats defines the configuration for the NATS notifications.
+type Nats struct {
+	URL     string `json:"url" yaml:"url"`
+	Subject string `json:"subject" yaml:"subject"`
+	Timeout int    `json:"timeout" yaml:"timeout"`
+}
+
+// Blackbox defines the configuration for the blackbox target mode.
+type Blackbox struct {
+	Enabled  bool   `json:"enabled" yaml:"enabled"`
+	Target   string `json:"target" yaml:"target"`
+	Module   string `json:"module" yaml:"module"`
+	Exporter string `json:"exporter" yaml:"exporter"`
+}
+
+// Subnets defines the configuration for subnet targets.
+type Subnets struct {
+	Enabled bool     `json:"enabled" yaml:"enabled"`
+	Exclude []string `json:"exclude" yaml:"exclude"`
+}
+
+// Patterns defines include and exclude patterns, either globs or regular
+// expressions wrapped in slashes.
+type Patterns struct {
+	Include []string `json:"include" yaml:"include"`
+	Exclude []string `json:"exclude" yaml:"exclude"`
+}
+
+// Numbers defines allow and deny lists of server numbers.
+type Numbers struct {
+	Allow []int `json:"allow" yaml:"allow"`
+	Deny  []int `json:"deny" yaml:"deny"`
+}
+
+// Cancelled defines the handling of cancelled servers.
+type Cancelled struct {
+	Exclude bool `json:"exclude" yaml:"exclude"`
+	Grace   int  `json:"grace" yaml:"grace"`
+}
+
+// Filters defines the configuration for filtering the servers.
+type Filters struct {
+	Datacenters []string  `json:"datacenters" yaml:"datacenters"`
+	Products    Patterns  `json:"products" yaml:"products"`
+	Names       Patterns  `json:"names" yaml:"names"`
+	Numbers     Numbers   `json:"numbers" yaml:"numbers"`
+	Statuses    []string  `json:"statuses" yaml:"statuses"`
+	Cancelled   Cancelled `json:"cancelled" yaml:"cancelled"`
+}
+
+// Relabel defines a relabel rule like the relabel configs of Prometheus.
+type Relabel struct {
+	SourceLabels []string `json:"source_labels" yaml:"source_labels,omitempty"`
+	Separator    string   `json:"separator" yaml:"separator,omitempty"`
+	Regex        string   `json:"regex" yaml:"regex,omitempty"`
+	Modulus      uint64   `json:"modulus" yaml:"modulus,omitempty"`
+	TargetLabel  string   `json:"target_label" yaml:"target_label,omitempty"`
+	Replacement  string   `json:"replacement" yaml:"replacement,omitempty"`
+	Action       string   `json:"action" yaml:"action,omitempty"`
+}
+
+// PortMapping defines a port for all targets with labels matching the
+// regular expressions.
+type PortMapping struct {
+	Name  string            `json:"name" yaml:"name"`
+	Port  int               `json:"port" yaml:"port"`
+	Match map[string]string `json:"match" yaml:"match"`
+}
+
+// StaticTarget defines additional targets merged into the output.
+type StaticTarget struct {
+	Targets []string          `json:"targets" yaml:"targets"`
+	Labels  map[string]string `json:"labels" yaml:"labels"`
+}
+
+// Budget defines the number of requests allowed by the API within an interval.
+type Budget struct {
+	Requests int      `json:"requests" yaml:"requests"`
+	Interval Duration `json:"interval" yaml:"interval"`
+}
+
+// Timeouts defines the timeouts for requests to the API.
+type Timeouts struct {
+	Request Duration `json:"request" yaml:"request"`
+	Connect Duration `json:"connect" yaml:"connect"`
+	Read    Duration `json:"read" yaml:"read"`
+}
+
+// Proxy defines the proxy for requests to the API.
+type Proxy struct {
+	URL      string `json:"url" yaml:"url"`
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+}
+
+// Transport defines the connection pool for requests to the API.
+type Transport struct {
+	MaxIdle        int      `json:"max_idle_conns" yaml:"max_idle_conns"`
+	MaxIdlePerHost int      `json:"max_idle_conns_per_host" yaml:"max_idle_conns_per_host"`
+	IdleTimeout    Duration `json:"idle_timeout" yaml:"idle_timeout"`
+	HTTP2          bool     `json:"http2" yaml:"http2"`
+}
+
+// TLS defines the TLS options for requests to the API.
+type TLS struct {
+	CAFile   string `json:"ca_file" yaml:"ca_file"`
+	CertFile string `json:"cert_file" yaml:"cert_file"`
+	KeyFile  string `json:"key_file" yaml:"key_file"`
+	Insecure bool   `json:"insecure_skip_verify" yaml:"insecure_skip_verify"`
+}
+
+// Retry defines the retries with exponential backoff for failed API requests.
+type Retry struct {
+	Attempts   int      `json:"attempts" yaml:"attempts"`
+	Initial    Duration `json:"initial" yaml:"initial"`
+	Multiplier float64  `json:"multiplier" yaml:"multiplier"`
+	Max        Duration `json:"max" yaml:"max"`
 }
 
 // Target defines the target specific configuration.
 type Target struct {
-	Engine      string       `json:"engine" yaml:"engine"`
-	File        string       `json:"file" yaml:"file"`
-	Refresh     int          `json:"refresh" yaml:"refresh"`
-	Credentials []Credential `json:"credentials" yaml:"credentials"`
+	Engine      string            `json:"engine" yaml:"engine"`
+	File        string            `json:"file" yaml:"file"`
+	State       string            `json:"state_file" yaml:"state_file"`
+	Refresh     Duration          `json:"refresh" yaml:"refresh"`
+	Jitter      float64           `json:"refresh_jitter" yaml:"refresh_jitter"`
+	Stagger     Duration          `json:"refresh_stagger" yaml:"refresh_stagger"`
+	Interval    Duration          `json:"interval" yaml:"interval"`
+	MaxTargets  int               `json:"max_targets" yaml:"max_targets"`
+	Split       string            `json:"split" yaml:"split"`
+	GroupBy     string            `json:"group_by" yaml:"group_by"`
+	Prefix      string            `json:"label_prefix" yaml:"label_prefix"`
+	Port        int               `json:"port" yaml:"port"`
+	Address     string            `json:"address" yaml:"address"`
+	Hostname    string            `json:"hostname" yaml:"hostname"`
+	Domain      string            `json:"domain" yaml:"domain"`
+	Ports       []PortMapping     `json:"ports" yaml:"ports"`
+	Indent      int               `json:"indent" yaml:"indent"`
+	Compact     bool              `json:"compact" yaml:"compact"`
+	Vars        map[string]string `json:"vars" yaml:"vars"`
+	Zookeeper   Zookeeper         `json:"zookeeper" yaml:"zookeeper"`
+	Kubernetes  Kubernetes        `json:"kubernetes" yaml:"kubernetes"`
+	S3          S3                `json:"s3" yaml:"s3"`
+	Redis       Redis             `json:"redis" yaml:"redis"`
+	Outputs     []Output          `json:"outputs" yaml:"outputs"`
+	DNS         DNS               `json:"dns" yaml:"dns"`
+	Webhook     Webhook           `json:"webhook" yaml:"webhook"`
+	Nats        Nats              `json:"nats" yaml:"nats"`
+	Blackbox    Blackbox          `json:"blackbox" yaml:"blackbox"`
+	Subnets     Subnets           `json:"subnets" yaml:"subnets"`
+	Budget      Budget            `json:"budget" yaml:"budget"`
+	Endpoint    string            `json:"endpoint" yaml:"endpoint"`
+	Retry       Retry             `json:"retry" yaml:"retry"`
+	Timeouts    Timeouts          `json:"timeouts" yaml:"timeouts"`
+	Proxy       Proxy             `json:"proxy" yaml:"proxy"`
+	TLS         TLS               `json:"tls" yaml:"tls"`
+	Transport   Transport         `json:"transport" yaml:"transport"`
+	StaleAfter  Duration          `json:"stale_after" yaml:"stale_after"`
+	Workers     int               `json:"workers" yaml:"workers"`
+	Timeout     Duration          `json:"project_timeout" yaml:"project_timeout"`
+	Filters     Filters           `json:"filters" yaml:"filters"`
+	Relabel     []Relabel         `json:"relabel" yaml:"relabel"`
+	Labels      Patterns          `json:"labels" yaml:"labels"`
+	Static      []StaticTarget    `json:"static_targets" yaml:"static_targets"`
+	Credentials []Credential      `json:"credentials" yaml:"credentials"`
 }
 
 // Config is a combination of all available configurations.
 type Config struct {
-	Server Server `json:"server" yaml:"server"`
-	Logs   Logs   `json:"logs" yaml:"logs"`
-	Target Target `json:"target" yaml:"target"`
+	Server   Server   `json:"server" yaml:"server"`
+	Logs     Logs     `json:"logs" yaml:"logs"`
+	Tracing  Tracing  `json:"tracing" yaml:"tracing"`
+	Audit    Audit    `json:"audit" yaml:"audit"`
+	Target   Target   `json:"target" yaml:"target"`
+	Features []string `json:"features" yaml:"features"`
+
+	// File defines the path of the configuration file.
+	File string `json:"-" yaml:"-"`
+
+	// Lenient disables the rejection of unknown keys within the file.
+	Lenient bool `json:"-" yaml:"-"`
+
+	// Watch enables the reload of the credentials on changes of the
+	// configuration file or the secret files.
+	Watch bool `json:"-" yaml:"-"`
+
+	// Defaults defines the credentials provided by flags, they are kept on
+	// reloads of the configuration file.
+	Defaults []Credential `json:"-" yaml:"-"`
 }
 
 // Load initializes a default configuration struct.
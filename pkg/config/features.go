@@ -0,0 +1,76 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+)
+
+var (
+	// Features defines all available experimental features.
+	Features = map[string]string{
+		"dns-server":   "Answer SRV and A queries for targets by an embedded DNS server",
+		"nats-output":  "Publish target changes to a NATS subject",
+		"redis-output": "Store targets within a Redis key",
+	}
+
+	// Stable defines former features which are always enabled, these names are
+	// still accepted to not break existing configurations.
+	Stable = map[string]string{
+		"kubernetes-output": "Write targets into Kubernetes resources",
+		"s3-output":         "Upload targets to an S3-compatible storage",
+		"zookeeper-output":  "Publish targets as ZooKeeper serversets",
+	}
+)
+
+// FeatureNames returns the sorted names of all available features.
+func FeatureNames() []string {
+	result := make([]string, 0, len(Features))
+
+	for name := range Features {
+		result = append(result, name)
+	}
+
+	sort.Strings(result)
+	return result
+}
+
+// ValidateFeatures checks that all enabled features are known.
+func (c *Config) ValidateFeatures() error {
+	for _, feature := range c.Features {
+		if _, ok := Features[feature]; ok {
+			continue
+		}
+
+		if _, ok := Stable[feature]; ok {
+			continue
+		}
+
+		return fmt.Errorf("unknown feature %q", feature)
+	}
+
+	return nil
+}
+
+// StableFeatures returns the enabled features which are always enabled.
+func (c *Config) StableFeatures() []string {
+	result := make([]string, 0)
+
+	for _, feature := range c.Features {
+		if _, ok := Stable[feature]; ok {
+			result = append(result, feature)
+		}
+	}
+
+	return result
+}
+
+// Enabled checks if the given experimental feature has been enabled.
+func (c *Config) Enabled(feature string) bool {
+	for _, enabled := range c.Features {
+		if enabled == feature {
+			return true
+		}
+	}
+
+	return false
+}
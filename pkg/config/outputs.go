@@ -0,0 +1,71 @@
+package config
+
+import (
+	"reflect"
+)
+
+// Output defines the configuration for an additional output.
+type Output struct {
+	Engine     string            `json:"engine" yaml:"engine"`
+	File       string            `json:"file" yaml:"file"`
+	Split      string            `json:"split" yaml:"split"`
+	GroupBy    string            `json:"group_by" yaml:"group_by"`
+	Indent     int               `json:"indent" yaml:"indent"`
+	Compact    bool              `json:"compact" yaml:"compact"`
+	Filters    map[string]string `json:"filters" yaml:"filters"`
+	Zookeeper  Zookeeper         `json:"zookeeper" yaml:"zookeeper"`
+	Kubernetes Kubernetes        `json:"kubernetes" yaml:"kubernetes"`
+	S3         S3                `json:"s3" yaml:"s3"`
+	Redis      Redis             `json:"redis" yaml:"redis"`
+}
+
+// Primary returns the output defined by the target configuration itself.
+func (t Target) Primary() Output {
+	return Output{
+		Engine:     t.Engine,
+		File:       t.File,
+		Split:      t.Split,
+		GroupBy:    t.GroupBy,
+		Indent:     t.Indent,
+		Compact:    t.Compact,
+		Zookeeper:  t.Zookeeper,
+		Kubernetes: t.Kubernetes,
+		S3:         t.S3,
+		Redis:      t.Redis,
+	}
+}
+
+// AllOutputs returns the primary output followed by all additional outputs,
+// unset engine options of additional outputs are inherited from the primary.
+func (t Target) AllOutputs() []Output {
+	primary := t.Primary()
+	result := make([]Output, 0, len(t.Outputs)+1)
+	result = append(result, primary)
+
+	for _, output := range t.Outputs {
+		if output.Indent == 0 && !output.Compact {
+			output.Indent = primary.Indent
+		}
+
+		inherit(&output.Zookeeper, primary.Zookeeper)
+		inherit(&output.Kubernetes, primary.Kubernetes)
+		inherit(&output.S3, primary.S3)
+		inherit(&output.Redis, primary.Redis)
+
+		result = append(result, output)
+	}
+
+	return result
+}
+
+// inherit sets all zero fields of dst to the matching values of src.
+func inherit(dst, src interface{}) {
+	d := reflect.ValueOf(dst).Elem()
+	s := reflect.ValueOf(src)
+
+	for i := 0; i < d.NumField(); i++ {
+		if d.Field(i).IsZero() {
+			d.Field(i).Set(s.Field(i))
+		}
+	}
+}
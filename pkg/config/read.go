@@ -0,0 +1,97 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"go.mozilla.org/sops/v3/decrypt"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	// ErrFormatInvalid defines the error if ext is unsupported.
+	ErrFormatInvalid = errors.New("config extension is not supported")
+)
+
+// secret returns the content of the file if defined, otherwise the value.
+func secret(value, file string) (string, error) {
+	if file == "" {
+		return value, nil
+	}
+
+	content, err := ioutil.ReadFile(file)
+
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(content)), nil
+}
+
+// Read parses the configuration file into the provided configuration, files
+// encrypted by SOPS get decrypted in memory. If strict is enabled unknown keys
+// are rejected.
+func Read(file string, cfg *Config, strict bool) error {
+	if file == "" {
+		return nil
+	}
+
+	content, err := ioutil.ReadFile(file)
+
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		if content, err = decrypted(content, "yaml", yaml.Unmarshal); err != nil {
+			return err
+		}
+
+		unmarshal := yaml.Unmarshal
+
+		if strict {
+			unmarshal = yaml.UnmarshalStrict
+		}
+
+		if err = unmarshal(content, cfg); err != nil {
+			return err
+		}
+	case ".json":
+		if content, err = decrypted(content, "json", json.Unmarshal); err != nil {
+			return err
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(content))
+
+		if strict {
+			decoder.DisallowUnknownFields()
+		}
+
+		if err = decoder.Decode(cfg); err != nil {
+			return err
+		}
+	default:
+		return ErrFormatInvalid
+	}
+
+	return nil
+}
+
+// decrypted decrypts the content by SOPS if it contains the SOPS metadata,
+// otherwise the content is returned unchanged.
+func decrypted(content []byte, format string, unmarshal func([]byte, interface{}) error) ([]byte, error) {
+	probe := struct {
+		Sops interface{} `json:"sops" yaml:"sops"`
+	}{}
+
+	if err := unmarshal(content, &probe); err != nil || probe.Sops == nil {
+		return content, nil
+	}
+
+	return decrypt.Data(content, format)
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"net/url"
+	"strings"
+)
+
+const (
+	// redacted defines the replacement for secrets.
+	redacted = "<secret>"
+)
+
+// Redacted returns a copy of the configuration with all passwords, secrets
+// and keys masked.
+func (c Config) Redacted() Config {
+	c.Target.Credentials = append([]Credential(nil), c.Target.Credentials...)
+
+	for i := range c.Target.Credentials {
+		c.Target.Credentials[i].Password = mask(c.Target.Credentials[i].Password)
+	}
+
+	c.Server.Tokens = append([]Token(nil), c.Server.Tokens...)
+
+	for i := range c.Server.Tokens {
+		c.Server.Tokens[i].Token = mask(c.Server.Tokens[i].Token)
+	}
+
+	c.Target.Endpoint = maskURL(c.Target.Endpoint)
+	c.Target.S3.Endpoint = maskURL(c.Target.S3.Endpoint)
+	c.Target.S3.SecretKey = mask(c.Target.S3.SecretKey)
+	c.Target.Redis.Password = mask(c.Target.Redis.Password)
+	c.Target.Webhook.URL = maskURL(c.Target.Webhook.URL)
+	c.Target.Webhook.Secret = mask(c.Target.Webhook.Secret)
+	c.Target.Nats.URL = maskURL(c.Target.Nats.URL)
+	c.Tracing.Endpoint = maskURL(c.Tracing.Endpoint)
+	c.Target.Proxy.URL = maskURL(c.Target.Proxy.URL)
+	c.Target.Proxy.Password = mask(c.Target.Proxy.Password)
+
+	c.Target.Outputs = append([]Output(nil), c.Target.Outputs...)
+
+	for i := range c.Target.Outputs {
+		c.Target.Outputs[i].S3.Endpoint = maskURL(c.Target.Outputs[i].S3.Endpoint)
+		c.Target.Outputs[i].S3.SecretKey = mask(c.Target.Outputs[i].S3.SecretKey)
+		c.Target.Outputs[i].Redis.Password = mask(c.Target.Outputs[i].Redis.Password)
+	}
+
+	c.Defaults = nil
+
+	return c
+}
+
+// mask replaces non-empty values by a placeholder.
+func mask(value string) string {
+	if value == "" {
+		return ""
+	}
+
+	return redacted
+}
+
+// maskURL masks the passwords embedded into an URL, comma-separated lists of
+// URLs like for NATS are masked individually.
+func maskURL(value string) string {
+	parts := strings.Split(value, ",")
+
+	for i, part := range parts {
+		u, err := url.Parse(part)
+
+		if err != nil {
+			continue
+		}
+
+		parts[i] = u.Redacted()
+	}
+
+	return strings.Join(parts, ",")
+}
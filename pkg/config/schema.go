@@ -0,0 +1,82 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+const (
+	// schemaDraft defines the JSON Schema draft used for the schema.
+	schemaDraft = "http://json-schema.org/draft-07/schema#"
+)
+
+// Schema generates a JSON Schema for the configuration file.
+func Schema() map[string]interface{} {
+	result := schemaFor(reflect.TypeOf(Config{}))
+
+	result["$schema"] = schemaDraft
+	result["title"] = "Prometheus Hetzner SD"
+
+	return result
+}
+
+func schemaFor(t reflect.Type) map[string]interface{} {
+	if t == reflect.TypeOf(Duration(0)) {
+		return map[string]interface{}{
+			"type": []string{"string", "integer"},
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return schemaFor(t.Elem())
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+
+			if name == "" || name == "-" {
+				continue
+			}
+
+			properties[name] = schemaFor(field.Type)
+		}
+
+		return map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": schemaFor(t.Elem()),
+		}
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": schemaFor(t.Elem()),
+		}
+	case reflect.Bool:
+		return map[string]interface{}{
+			"type": "boolean",
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{
+			"type": "integer",
+		}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{
+			"type": "number",
+		}
+	case reflect.String:
+		return map[string]interface{}{
+			"type": "string",
+		}
+	default:
+		return map[string]interface{}{}
+	}
+}
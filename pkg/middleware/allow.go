@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// Allow initializes a middleware to reject all requests from remote addresses
+// outside of the allowed networks. It must be used before the RealIP
+// middleware, otherwise the check could be bypassed by forwarded headers.
+func Allow(networks []*net.IPNet, logger log.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if !allowed(networks, r.RemoteAddr) {
+				level.Debug(logger).Log(
+					"msg", "Rejected request from disallowed address",
+					"remote", r.RemoteAddr,
+					"path", r.URL.Path,
+				)
+
+				http.Error(
+					w,
+					http.StatusText(http.StatusForbidden),
+					http.StatusForbidden,
+				)
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+func allowed(networks []*net.IPNet, addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+
+	if err != nil {
+		host = addr
+	}
+
+	ip := net.ParseIP(host)
+
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
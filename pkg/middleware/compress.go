@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+var compressor = middleware.NewCompressor(
+	5,
+	"application/json",
+	"text/html",
+	"text/plain",
+)
+
+// Compress compresses the responses if the client accepts it, responses
+// already compressed like the metrics are passed through.
+func Compress(next http.Handler) http.Handler {
+	return compressor.Handler(next)
+}
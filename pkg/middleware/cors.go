@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+var (
+	// defaultMethods defines the allowed methods if nothing is configured.
+	defaultMethods = []string{http.MethodGet, http.MethodHead}
+)
+
+// CORS initializes a middleware to write the CORS headers for the allowed
+// origins, a wildcard allows any origin without credentials. Only origins
+// listed by name are allowed to send credentials. Preflight requests get
+// answered directly.
+func CORS(origins, methods []string) func(next http.Handler) http.Handler {
+	if len(methods) == 0 {
+		methods = defaultMethods
+	}
+
+	allowed := strings.Join(methods, ", ")
+
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Add("Vary", "Origin")
+
+			switch matchOrigin(origins, origin) {
+			case originListed:
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			case originWildcard:
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			default:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.Header().Set("Access-Control-Allow-Methods", allowed)
+				w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+				w.Header().Set("Access-Control-Max-Age", "600")
+				w.WriteHeader(http.StatusNoContent)
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+const (
+	originDenied = iota
+	originWildcard
+	originListed
+)
+
+// matchOrigin returns if the origin is listed by name or only allowed by a
+// wildcard, a listed origin takes precedence over the wildcard.
+func matchOrigin(origins []string, origin string) int {
+	result := originDenied
+
+	for _, allowed := range origins {
+		if strings.EqualFold(allowed, origin) {
+			return originListed
+		}
+
+		if allowed == "*" {
+			result = originWildcard
+		}
+	}
+
+	return result
+}
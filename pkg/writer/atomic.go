@@ -0,0 +1,55 @@
+package writer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// WriteAtomic writes the content to a temporary file within the directory of
+// the file, syncs it and renames it to the file afterwards. This way readers
+// like Prometheus never observe a partially written file, not even after a
+// crash. The mode of an existing file is preserved.
+func WriteAtomic(name string, content []byte) error {
+	dir := filepath.Dir(name)
+	mode := os.FileMode(0644)
+
+	if info, err := os.Stat(name); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmpfile, err := ioutil.TempFile(dir, "."+filepath.Base(name)+".")
+
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tmpfile.Close()
+			os.Remove(tmpfile.Name())
+		}
+	}()
+
+	if _, err = tmpfile.Write(content); err != nil {
+		return err
+	}
+
+	if err = tmpfile.Chmod(mode); err != nil {
+		return err
+	}
+
+	if err = tmpfile.Sync(); err != nil {
+		return err
+	}
+
+	if err = tmpfile.Close(); err != nil {
+		return err
+	}
+
+	if err = os.Rename(tmpfile.Name(), name); err != nil {
+		return err
+	}
+
+	return syncDir(dir)
+}
@@ -0,0 +1,20 @@
+//go:build !windows
+// +build !windows
+
+package writer
+
+import (
+	"os"
+)
+
+// syncDir syncs the directory to persist a renamed file.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+
+	if err != nil {
+		return err
+	}
+
+	defer d.Close()
+	return d.Sync()
+}
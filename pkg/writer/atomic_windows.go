@@ -0,0 +1,10 @@
+//go:build windows
+// +build windows
+
+package writer
+
+// syncDir is a no-op as directories can't be synced on Windows, the rename
+// is persisted by the file system anyway.
+func syncDir(dir string) error {
+	return nil
+}
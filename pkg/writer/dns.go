@@ -0,0 +1,260 @@
+package writer
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/miekg/dns"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
+)
+
+var (
+	// dnsLabelRegexp defines the chars replaced within DNS labels.
+	dnsLabelRegexp = regexp.MustCompile(`[^a-z0-9-]+`)
+)
+
+// DNSLabels defines the label names used to build the DNS records.
+type DNSLabels struct {
+	Name       string
+	Datacenter string
+}
+
+// DNS answers SRV and A/AAAA queries for the targets by an embedded server.
+type DNS struct {
+	domain  string
+	service string
+	port    int
+	ttl     uint32
+	labels  DNSLabels
+	logger  log.Logger
+	mutex   sync.RWMutex
+	records map[uint16]map[string][]dns.RR
+	servers []*dns.Server
+}
+
+// Write implements the Writer interface.
+func (d *DNS) Write(groups []Group) error {
+	records := map[uint16]map[string][]dns.RR{
+		dns.TypeA:    {},
+		dns.TypeAAAA: {},
+		dns.TypeSRV:  {},
+	}
+
+	names := make(map[string]struct{})
+
+	for _, group := range groups {
+		for _, target := range group.Targets {
+			host, port := d.splitTarget(target)
+			ip := net.ParseIP(host)
+
+			if ip == nil {
+				continue
+			}
+
+			name := d.hostname(group.Labels[d.labels.Name], ip, names)
+			names[name] = struct{}{}
+
+			if v4 := ip.To4(); v4 != nil {
+				records[dns.TypeA][name] = append(records[dns.TypeA][name], &dns.A{
+					Hdr: d.header(name, dns.TypeA),
+					A:   v4,
+				})
+			} else {
+				records[dns.TypeAAAA][name] = append(records[dns.TypeAAAA][name], &dns.AAAA{
+					Hdr:  d.header(name, dns.TypeAAAA),
+					AAAA: ip,
+				})
+			}
+
+			services := []string{
+				d.fqdn("_" + d.service + "._tcp"),
+			}
+
+			if dc := group.Labels[d.labels.Datacenter]; dc != "" {
+				services = append(services, d.fqdn("_"+d.service+"._tcp."+d.label(dc)))
+			}
+
+			for _, service := range services {
+				records[dns.TypeSRV][service] = append(records[dns.TypeSRV][service], &dns.SRV{
+					Hdr:      d.header(service, dns.TypeSRV),
+					Priority: 0,
+					Weight:   0,
+					Port:     uint16(port),
+					Target:   name,
+				})
+			}
+		}
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.records = records
+	return nil
+}
+
+// ServeDNS implements the dns.Handler interface.
+func (d *DNS) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Authoritative = true
+
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	for _, q := range r.Question {
+		name := strings.ToLower(q.Name)
+
+		switch q.Qtype {
+		case dns.TypeSRV:
+			for _, rr := range d.records[dns.TypeSRV][name] {
+				m.Answer = append(m.Answer, rr)
+				target := rr.(*dns.SRV).Target
+
+				m.Extra = append(m.Extra, d.records[dns.TypeA][target]...)
+				m.Extra = append(m.Extra, d.records[dns.TypeAAAA][target]...)
+			}
+		case dns.TypeA, dns.TypeAAAA:
+			m.Answer = append(m.Answer, d.records[q.Qtype][name]...)
+		}
+
+		if len(m.Answer) == 0 && !d.exists(name) {
+			m.Rcode = dns.RcodeNameError
+		}
+	}
+
+	if err := w.WriteMsg(m); err != nil {
+		level.Debug(d.logger).Log(
+			"msg", "Failed to write DNS response",
+			"err", err,
+		)
+	}
+}
+
+func (d *DNS) exists(name string) bool {
+	for _, records := range d.records {
+		if _, ok := records[name]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (d *DNS) header(name string, rrtype uint16) dns.RR_Header {
+	return dns.RR_Header{
+		Name:   name,
+		Rrtype: rrtype,
+		Class:  dns.ClassINET,
+		Ttl:    d.ttl,
+	}
+}
+
+func (d *DNS) hostname(name string, ip net.IP, names map[string]struct{}) string {
+	if label := d.label(name); label != "" {
+		if _, ok := names[d.fqdn(label)]; !ok {
+			return d.fqdn(label)
+		}
+	}
+
+	return d.fqdn(d.label(ip.String()))
+}
+
+func (d *DNS) label(value string) string {
+	return strings.Trim(dnsLabelRegexp.ReplaceAllString(strings.ToLower(value), "-"), "-")
+}
+
+func (d *DNS) fqdn(name string) string {
+	return dns.Fqdn(name + "." + d.domain)
+}
+
+func (d *DNS) splitTarget(target string) (string, int) {
+	host, raw, err := net.SplitHostPort(target)
+
+	if err != nil {
+		return target, d.port
+	}
+
+	port, err := strconv.Atoi(raw)
+
+	if err != nil {
+		return host, d.port
+	}
+
+	return host, port
+}
+
+// Serve answers the queries by the servers until they get stopped.
+func (d *DNS) Serve() error {
+	errs := make(chan error, len(d.servers))
+
+	for _, server := range d.servers {
+		go func(server *dns.Server) {
+			errs <- server.ActivateAndServe()
+		}(server)
+	}
+
+	return <-errs
+}
+
+// Stop shuts down all servers.
+func (d *DNS) Stop() {
+	for _, server := range d.servers {
+		if err := server.Shutdown(); err != nil {
+			level.Debug(d.logger).Log(
+				"msg", "Failed to shutdown DNS server",
+				"net", server.Net,
+				"err", err,
+			)
+		}
+	}
+}
+
+// NewDNS creates a new DNS writer and binds the listeners of the embedded
+// server, the queries are answered once the server gets served.
+func NewDNS(cfg config.DNS, labels DNSLabels, logger log.Logger) (*DNS, error) {
+	d := &DNS{
+		domain:  strings.ToLower(strings.Trim(cfg.Domain, ".")),
+		port:    cfg.Port,
+		ttl:     uint32(cfg.TTL),
+		labels:  labels,
+		logger:  logger,
+		records: make(map[uint16]map[string][]dns.RR),
+	}
+
+	d.service = d.label(cfg.Service)
+
+	packet, err := net.ListenPacket("udp", cfg.Addr)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for dns on udp: %w", err)
+	}
+
+	listener, err := net.Listen("tcp", cfg.Addr)
+
+	if err != nil {
+		packet.Close()
+		return nil, fmt.Errorf("failed to listen for dns on tcp: %w", err)
+	}
+
+	d.servers = []*dns.Server{
+		{
+			PacketConn: packet,
+			Net:        "udp",
+			Handler:    d,
+		},
+		{
+			Listener: listener,
+			Net:      "tcp",
+			Handler:  d,
+		},
+	}
+
+	return d, nil
+}
@@ -0,0 +1,175 @@
+package writer
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+var (
+	// shardRegexp defines the chars replaced within templated file names.
+	shardRegexp = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+)
+
+// FileLabels defines the label names exposed to templated file names.
+type FileLabels struct {
+	Project    string
+	Datacenter string
+	Location   string
+}
+
+type fileData struct {
+	Project    string
+	Datacenter string
+	Location   string
+	Labels     map[string]string
+	Vars       map[string]string
+}
+
+// File writes the target groups as JSON to a file for file_sd.
+type File struct {
+	output   string
+	template *template.Template
+	vars     map[string]string
+	labels   FileLabels
+	indent   int
+	shards   map[string]struct{}
+}
+
+// Write implements the Writer interface.
+func (f *File) Write(groups []Group) error {
+	if f.template == nil {
+		return f.writeFile(f.output, groups)
+	}
+
+	shards := make(map[string][]Group)
+
+	for _, group := range groups {
+		name, err := f.shardFile(group)
+
+		if err != nil {
+			return err
+		}
+
+		shards[name] = append(shards[name], group)
+	}
+
+	names := make([]string, 0, len(shards))
+
+	for name := range shards {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := f.writeFile(name, shards[name]); err != nil {
+			return err
+		}
+	}
+
+	for name := range f.shards {
+		if _, ok := shards[name]; ok {
+			continue
+		}
+
+		if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	f.shards = make(map[string]struct{}, len(shards))
+
+	for name := range shards {
+		f.shards[name] = struct{}{}
+	}
+
+	return nil
+}
+
+func (f *File) shardFile(group Group) (string, error) {
+	labels := make(map[string]string, len(group.Labels))
+
+	for key, value := range group.Labels {
+		labels[key] = sanitizeShard(value)
+	}
+
+	buf := bytes.NewBufferString("")
+
+	if err := f.template.Execute(buf, fileData{
+		Project:    labels[f.labels.Project],
+		Datacenter: labels[f.labels.Datacenter],
+		Location:   labels[f.labels.Location],
+		Labels:     labels,
+		Vars:       f.vars,
+	}); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func sanitizeShard(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+
+	return shardRegexp.ReplaceAllString(value, "_")
+}
+
+func (f *File) marshal(groups []Group) ([]byte, error) {
+	if f.indent <= 0 {
+		return json.Marshal(groups)
+	}
+
+	b, err := json.MarshalIndent(groups, "", strings.Repeat(" ", f.indent))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append(b, '\n'), nil
+}
+
+func (f *File) writeFile(output string, groups []Group) error {
+	b, err := f.marshal(groups)
+	if err != nil {
+		return err
+	}
+
+	return WriteAtomic(output, b)
+}
+
+// IsTemplate checks if the given file name is a template.
+func IsTemplate(file string) bool {
+	return strings.Contains(file, "{{")
+}
+
+// NewFile creates a new file writer, if the file name is a template the
+// targets are written into separate files per rendered file name. An indent
+// of zero writes compact JSON.
+func NewFile(file string, vars map[string]string, labels FileLabels, indent int) (*File, error) {
+	f := &File{
+		output: file,
+		vars:   vars,
+		labels: labels,
+		indent: indent,
+		shards: make(map[string]struct{}),
+	}
+
+	if IsTemplate(file) {
+		tmpl, err := template.New("file").Option("missingkey=zero").Parse(file)
+
+		if err != nil {
+			return nil, err
+		}
+
+		f.template = tmpl
+	}
+
+	return f, nil
+}
@@ -0,0 +1,59 @@
+package writer
+
+import (
+	"regexp"
+)
+
+// Filter only passes the target groups matching all filters to a writer.
+type Filter struct {
+	writer  Writer
+	filters map[string]*regexp.Regexp
+}
+
+// Write implements the Writer interface.
+func (f *Filter) Write(groups []Group) error {
+	result := make([]Group, 0, len(groups))
+
+	for _, group := range groups {
+		if f.matches(group) {
+			result = append(result, group)
+		}
+	}
+
+	return f.writer.Write(result)
+}
+
+func (f *Filter) matches(group Group) bool {
+	for label, filter := range f.filters {
+		if !filter.MatchString(group.Labels[label]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// NewFilter wraps a writer to only write target groups where the labels are
+// matching the regular expressions of all filters.
+func NewFilter(w Writer, filters map[string]string) (Writer, error) {
+	if len(filters) == 0 {
+		return w, nil
+	}
+
+	f := &Filter{
+		writer:  w,
+		filters: make(map[string]*regexp.Regexp, len(filters)),
+	}
+
+	for label, filter := range filters {
+		re, err := regexp.Compile("^(?:" + filter + ")$")
+
+		if err != nil {
+			return nil, err
+		}
+
+		f.filters[label] = re
+	}
+
+	return f, nil
+}
@@ -0,0 +1,70 @@
+package writer
+
+import (
+	"sort"
+)
+
+// GroupBy bundles the target groups by the value of a label, only the labels
+// shared by all bundled groups are kept.
+type GroupBy struct {
+	writer Writer
+	label  string
+}
+
+// Write implements the Writer interface.
+func (g *GroupBy) Write(groups []Group) error {
+	buckets := make(map[string]*Group)
+	keys := make([]string, 0)
+
+	for _, group := range groups {
+		key := ""
+
+		if g.label != "" {
+			key = group.Labels[g.label]
+		}
+
+		bucket, ok := buckets[key]
+
+		if !ok {
+			labels := make(map[string]string, len(group.Labels))
+
+			for name, value := range group.Labels {
+				labels[name] = value
+			}
+
+			buckets[key] = &Group{
+				Targets: append([]string{}, group.Targets...),
+				Labels:  labels,
+			}
+
+			keys = append(keys, key)
+			continue
+		}
+
+		bucket.Targets = append(bucket.Targets, group.Targets...)
+
+		for name, value := range bucket.Labels {
+			if other, ok := group.Labels[name]; !ok || other != value {
+				delete(bucket.Labels, name)
+			}
+		}
+	}
+
+	sort.Strings(keys)
+	result := make([]Group, 0, len(keys))
+
+	for _, key := range keys {
+		result = append(result, *buckets[key])
+	}
+
+	return g.writer.Write(result)
+}
+
+// NewGroupBy wraps a writer to bundle the target groups by the value of a
+// label, an empty label bundles all targets into a single group.
+func NewGroupBy(w Writer, label string) *GroupBy {
+	return &GroupBy{
+		writer: w,
+		label:  label,
+	}
+}
@@ -0,0 +1,237 @@
+package writer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// namespaceFile defines the location of the namespace within a pod.
+	namespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+var (
+	// scrapeConfigResource defines the prometheus-operator ScrapeConfig resource.
+	scrapeConfigResource = schema.GroupVersionResource{
+		Group:    "monitoring.coreos.com",
+		Version:  "v1alpha1",
+		Resource: "scrapeconfigs",
+	}
+)
+
+var (
+	// ErrKubernetesKindInvalid defines the error if the kind is unsupported.
+	ErrKubernetesKindInvalid = errors.New("kubernetes kind is not supported")
+)
+
+// Kubernetes writes the targets as JSON into a ConfigMap or Secret, or as
+// static configs into a prometheus-operator ScrapeConfig resource.
+type Kubernetes struct {
+	client    kubernetes.Interface
+	dynamic   dynamic.Interface
+	namespace string
+	name      string
+	key       string
+	kind      string
+	timeout   time.Duration
+}
+
+// Write implements the Writer interface.
+func (k *Kubernetes) Write(groups []Group) error {
+	b, _ := json.MarshalIndent(groups, "", "    ")
+
+	ctx, cancel := context.WithTimeout(context.Background(), k.timeout)
+	defer cancel()
+
+	switch k.kind {
+	case "secret":
+		return k.writeSecret(ctx, b)
+	case "scrapeconfig":
+		return k.writeScrapeConfig(ctx, groups)
+	default:
+		return k.writeConfigMap(ctx, b)
+	}
+}
+
+func (k *Kubernetes) writeConfigMap(ctx context.Context, content []byte) error {
+	client := k.client.CoreV1().ConfigMaps(k.namespace)
+	current, err := client.Get(ctx, k.name, metav1.GetOptions{})
+
+	if apierrors.IsNotFound(err) {
+		_, err = client.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      k.name,
+				Namespace: k.namespace,
+			},
+			Data: map[string]string{
+				k.key: string(content),
+			},
+		}, metav1.CreateOptions{})
+
+		return err
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if current.Data == nil {
+		current.Data = make(map[string]string)
+	}
+
+	current.Data[k.key] = string(content)
+	_, err = client.Update(ctx, current, metav1.UpdateOptions{})
+
+	return err
+}
+
+func (k *Kubernetes) writeSecret(ctx context.Context, content []byte) error {
+	client := k.client.CoreV1().Secrets(k.namespace)
+	current, err := client.Get(ctx, k.name, metav1.GetOptions{})
+
+	if apierrors.IsNotFound(err) {
+		_, err = client.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      k.name,
+				Namespace: k.namespace,
+			},
+			Data: map[string][]byte{
+				k.key: content,
+			},
+		}, metav1.CreateOptions{})
+
+		return err
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if current.Data == nil {
+		current.Data = make(map[string][]byte)
+	}
+
+	current.Data[k.key] = content
+	_, err = client.Update(ctx, current, metav1.UpdateOptions{})
+
+	return err
+}
+
+func (k *Kubernetes) writeScrapeConfig(ctx context.Context, groups []Group) error {
+	configs := make([]interface{}, 0, len(groups))
+
+	for _, group := range groups {
+		targets := make([]interface{}, 0, len(group.Targets))
+
+		for _, target := range group.Targets {
+			targets = append(targets, target)
+		}
+
+		labels := make(map[string]interface{}, len(group.Labels))
+
+		for name, value := range group.Labels {
+			labels[name] = value
+		}
+
+		configs = append(configs, map[string]interface{}{
+			"targets": targets,
+			"labels":  labels,
+		})
+	}
+
+	client := k.dynamic.Resource(scrapeConfigResource).Namespace(k.namespace)
+	current, err := client.Get(ctx, k.name, metav1.GetOptions{})
+
+	if apierrors.IsNotFound(err) {
+		_, err = client.Create(ctx, &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"apiVersion": "monitoring.coreos.com/v1alpha1",
+				"kind":       "ScrapeConfig",
+				"metadata": map[string]interface{}{
+					"name":      k.name,
+					"namespace": k.namespace,
+				},
+				"spec": map[string]interface{}{
+					"staticConfigs": configs,
+				},
+			},
+		}, metav1.CreateOptions{})
+
+		return err
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := unstructured.SetNestedSlice(current.Object, configs, "spec", "staticConfigs"); err != nil {
+		return err
+	}
+
+	_, err = client.Update(ctx, current, metav1.UpdateOptions{})
+
+	return err
+}
+
+// NewKubernetes creates a new Kubernetes writer based on in-cluster credentials.
+func NewKubernetes(cfg config.Kubernetes) (*Kubernetes, error) {
+	switch cfg.Kind {
+	case "configmap", "secret", "scrapeconfig":
+	default:
+		return nil, ErrKubernetesKindInvalid
+	}
+
+	restConfig, err := rest.InClusterConfig()
+
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := kubernetes.NewForConfig(restConfig)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dyn, err := dynamic.NewForConfig(restConfig)
+
+	if err != nil {
+		return nil, err
+	}
+
+	namespace := cfg.Namespace
+
+	if namespace == "" {
+		content, err := ioutil.ReadFile(namespaceFile)
+
+		if err != nil {
+			return nil, err
+		}
+
+		namespace = strings.TrimSpace(string(content))
+	}
+
+	return &Kubernetes{
+		client:    client,
+		dynamic:   dyn,
+		namespace: namespace,
+		name:      cfg.Name,
+		key:       cfg.Key,
+		kind:      cfg.Kind,
+		timeout:   10 * time.Second,
+	}, nil
+}
@@ -0,0 +1,88 @@
+package writer
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
+)
+
+// Nats publishes the target groups together with a diff to a NATS subject.
+type Nats struct {
+	conn    *nats.Conn
+	subject string
+	timeout time.Duration
+	groups  []byte
+	last    map[string]struct{}
+}
+
+// Write implements the Writer interface.
+func (n *Nats) Write(groups []Group) error {
+	g, err := json.Marshal(groups)
+
+	if err != nil {
+		return err
+	}
+
+	if n.groups != nil && bytes.Equal(g, n.groups) {
+		return nil
+	}
+
+	current := make(map[string]struct{})
+
+	for _, group := range groups {
+		for _, target := range group.Targets {
+			current[target] = struct{}{}
+		}
+	}
+
+	b, err := json.Marshal(webhookPayload{
+		Timestamp: time.Now().UTC(),
+		Groups:    groups,
+		Diff: webhookDiff{
+			Added:   difference(current, n.last),
+			Removed: difference(n.last, current),
+		},
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if err := n.conn.Publish(n.subject, b); err != nil {
+		return err
+	}
+
+	if err := n.conn.FlushTimeout(n.timeout); err != nil {
+		return err
+	}
+
+	n.groups = g
+	n.last = current
+	return nil
+}
+
+// NewNats creates a new NATS writer.
+func NewNats(cfg config.Nats) (*Nats, error) {
+	timeout := time.Duration(cfg.Timeout) * time.Second
+
+	conn, err := nats.Connect(
+		cfg.URL,
+		nats.Name("prometheus-hetzner-sd"),
+		nats.Timeout(timeout),
+		nats.MaxReconnects(-1),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Nats{
+		conn:    conn,
+		subject: cfg.Subject,
+		timeout: timeout,
+		last:    make(map[string]struct{}),
+	}, nil
+}
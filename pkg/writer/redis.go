@@ -0,0 +1,60 @@
+package writer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
+)
+
+// Redis stores the target groups as JSON within a Redis key.
+type Redis struct {
+	client  *redis.Client
+	key     string
+	channel string
+	timeout time.Duration
+	last    []byte
+}
+
+// Write implements the Writer interface.
+func (r *Redis) Write(groups []Group) error {
+	b, _ := json.MarshalIndent(groups, "", "    ")
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	if err := r.client.Set(ctx, r.key, b, 0).Err(); err != nil {
+		return err
+	}
+
+	if r.channel != "" && !bytes.Equal(b, r.last) {
+		if err := r.client.Publish(ctx, r.channel, b).Err(); err != nil {
+			return err
+		}
+	}
+
+	r.last = b
+	return nil
+}
+
+// NewRedis creates a new Redis writer.
+func NewRedis(cfg config.Redis) *Redis {
+	timeout := time.Duration(cfg.Timeout) * time.Second
+
+	return &Redis{
+		client: redis.NewClient(&redis.Options{
+			Addr:         cfg.Addr,
+			Password:     cfg.Password,
+			DB:           cfg.DB,
+			DialTimeout:  timeout,
+			ReadTimeout:  timeout,
+			WriteTimeout: timeout,
+		}),
+		key:     cfg.Key,
+		channel: cfg.Channel,
+		timeout: timeout,
+	}
+}
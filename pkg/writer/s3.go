@@ -0,0 +1,79 @@
+package writer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
+)
+
+// S3 uploads the target groups as JSON to an S3-compatible object storage.
+type S3 struct {
+	client  *s3.S3
+	bucket  string
+	key     string
+	timeout time.Duration
+}
+
+// Write implements the Writer interface.
+func (o *S3) Write(groups []Group) error {
+	b, _ := json.MarshalIndent(groups, "", "    ")
+
+	ctx, cancel := context.WithTimeout(context.Background(), o.timeout)
+	defer cancel()
+
+	_, err := o.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(o.bucket),
+		Key:         aws.String(o.key),
+		Body:        bytes.NewReader(b),
+		ContentType: aws.String("application/json"),
+	})
+
+	return err
+}
+
+// NewS3 creates a new S3 writer.
+func NewS3(cfg config.S3) (*S3, error) {
+	awsConfig := aws.NewConfig().
+		WithRegion(cfg.Region).
+		WithS3ForcePathStyle(cfg.PathStyle)
+
+	if cfg.Endpoint != "" {
+		awsConfig = awsConfig.WithEndpoint(cfg.Endpoint)
+	}
+
+	if cfg.AccessKey != "" && cfg.SecretKey != "" {
+		awsConfig = awsConfig.WithCredentials(
+			credentials.NewStaticCredentials(
+				cfg.AccessKey,
+				cfg.SecretKey,
+				"",
+			),
+		)
+	}
+
+	timeout := time.Duration(cfg.Timeout) * time.Second
+
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	sess, err := session.NewSession(awsConfig)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &S3{
+		client:  s3.New(sess),
+		bucket:  cfg.Bucket,
+		key:     cfg.Key,
+		timeout: timeout,
+	}, nil
+}
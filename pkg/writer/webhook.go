@@ -0,0 +1,123 @@
+package writer
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
+)
+
+const (
+	// webhookSignatureHeader defines the header containing the HMAC signature.
+	webhookSignatureHeader = "X-Hetzner-SD-Signature"
+)
+
+type webhookDiff struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+type webhookPayload struct {
+	Timestamp time.Time   `json:"timestamp"`
+	Groups    []Group     `json:"groups"`
+	Diff      webhookDiff `json:"diff"`
+}
+
+// Webhook posts the target groups together with a diff to a webhook.
+type Webhook struct {
+	client *http.Client
+	url    string
+	secret string
+	last   map[string]struct{}
+}
+
+// Write implements the Writer interface.
+func (h *Webhook) Write(groups []Group) error {
+	current := make(map[string]struct{})
+
+	for _, group := range groups {
+		for _, target := range group.Targets {
+			current[target] = struct{}{}
+		}
+	}
+
+	payload := webhookPayload{
+		Timestamp: time.Now().UTC(),
+		Groups:    groups,
+		Diff: webhookDiff{
+			Added:   difference(current, h.last),
+			Removed: difference(h.last, current),
+		},
+	}
+
+	b, err := json.Marshal(payload)
+
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, h.url, bytes.NewReader(b))
+
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if h.secret != "" {
+		mac := hmac.New(sha256.New, []byte(h.secret))
+		mac.Write(b)
+
+		req.Header.Set(
+			webhookSignatureHeader,
+			"sha256="+hex.EncodeToString(mac.Sum(nil)),
+		)
+	}
+
+	resp, err := h.client.Do(req)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	h.last = current
+	return nil
+}
+
+func difference(a, b map[string]struct{}) []string {
+	result := make([]string, 0)
+
+	for key := range a {
+		if _, ok := b[key]; !ok {
+			result = append(result, key)
+		}
+	}
+
+	sort.Strings(result)
+	return result
+}
+
+// NewWebhook creates a new webhook writer.
+func NewWebhook(cfg config.Webhook) *Webhook {
+	return &Webhook{
+		client: &http.Client{
+			Timeout: time.Duration(cfg.Timeout) * time.Second,
+		},
+		url:    cfg.URL,
+		secret: cfg.Secret,
+		last:   make(map[string]struct{}),
+	}
+}
@@ -0,0 +1,12 @@
+package writer
+
+// Group defines a single target group in the file_sd format.
+type Group struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// Writer defines the interface for all output engines.
+type Writer interface {
+	Write([]Group) error
+}
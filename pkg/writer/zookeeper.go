@@ -0,0 +1,224 @@
+package writer
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/go-zookeeper/zk"
+	"github.com/promhippie/prometheus-hetzner-sd/pkg/config"
+)
+
+type serversetEndpoint struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+}
+
+type serversetMember struct {
+	ServiceEndpoint     serversetEndpoint            `json:"serviceEndpoint"`
+	AdditionalEndpoints map[string]serversetEndpoint `json:"additionalEndpoints"`
+	Status              string                       `json:"status"`
+	Shard               int                          `json:"shard"`
+}
+
+type zkLogger struct {
+	logger log.Logger
+}
+
+func (zl zkLogger) Printf(s string, i ...interface{}) {
+	level.Debug(zl.logger).Log(
+		"msg", fmt.Sprintf(s, i...),
+	)
+}
+
+// Zookeeper writes the targets as serverset members to a ZooKeeper znode.
+type Zookeeper struct {
+	conn    *zk.Conn
+	path    string
+	port    int
+	logger  log.Logger
+	members map[string][]byte
+	mutex   sync.Mutex
+}
+
+// Write implements the Writer interface.
+func (z *Zookeeper) Write(groups []Group) error {
+	members := make(map[string][]byte)
+
+	for _, group := range groups {
+		for _, target := range group.Targets {
+			host, port := z.splitTarget(target)
+
+			content, err := json.Marshal(serversetMember{
+				ServiceEndpoint: serversetEndpoint{
+					Host: host,
+					Port: port,
+				},
+				AdditionalEndpoints: map[string]serversetEndpoint{},
+				Status:              "ALIVE",
+			})
+
+			if err != nil {
+				return err
+			}
+
+			members[fmt.Sprintf("member_%x", sha1.Sum([]byte(target)))] = content
+		}
+	}
+
+	z.mutex.Lock()
+	defer z.mutex.Unlock()
+
+	z.members = members
+	return z.publish(members)
+}
+
+// publish syncs the serverset members with the children of the znode.
+func (z *Zookeeper) publish(members map[string][]byte) error {
+	if err := z.ensurePath(); err != nil {
+		return err
+	}
+
+	children, _, err := z.conn.Children(z.path)
+
+	if err != nil {
+		return err
+	}
+
+	for _, child := range children {
+		if _, ok := members[child]; ok || !strings.HasPrefix(child, "member_") {
+			continue
+		}
+
+		if err := z.conn.Delete(path.Join(z.path, child), -1); err != nil && err != zk.ErrNoNode {
+			return err
+		}
+
+		level.Debug(z.logger).Log(
+			"msg", "Removed serverset member",
+			"member", child,
+		)
+	}
+
+	for name, content := range members {
+		node := path.Join(z.path, name)
+
+		if _, err := z.conn.Create(node, content, zk.FlagEphemeral, zk.WorldACL(zk.PermAll)); err != nil {
+			if err != zk.ErrNodeExists {
+				return err
+			}
+
+			if _, err := z.conn.Set(node, content, -1); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (z *Zookeeper) ensurePath() error {
+	current := ""
+
+	for _, part := range strings.Split(strings.Trim(z.path, "/"), "/") {
+		current = current + "/" + part
+
+		if _, err := z.conn.Create(current, []byte{}, 0, zk.WorldACL(zk.PermAll)); err != nil && err != zk.ErrNodeExists {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (z *Zookeeper) splitTarget(target string) (string, int) {
+	host, raw, err := net.SplitHostPort(target)
+
+	if err != nil {
+		return target, z.port
+	}
+
+	port, err := strconv.Atoi(raw)
+
+	if err != nil {
+		return host, z.port
+	}
+
+	return host, port
+}
+
+// watch republishes the last members after a new session got established,
+// the ephemeral members of an expired session are gone already.
+func (z *Zookeeper) watch(events <-chan zk.Event) {
+	session := int64(0)
+
+	for event := range events {
+		if event.Type != zk.EventSession || event.State != zk.StateHasSession {
+			continue
+		}
+
+		previous := session
+		session = z.conn.SessionID()
+
+		if previous == 0 || previous == session {
+			continue
+		}
+
+		z.mutex.Lock()
+
+		if z.members != nil {
+			if err := z.publish(z.members); err != nil {
+				level.Error(z.logger).Log(
+					"msg", "Failed to republish serverset members",
+					"err", err,
+				)
+			} else {
+				level.Info(z.logger).Log(
+					"msg", "Republished serverset members after new session",
+					"members", len(z.members),
+				)
+			}
+		}
+
+		z.mutex.Unlock()
+	}
+}
+
+// Close closes the session, this way the ephemeral members get removed right
+// away instead of after the session timeout.
+func (z *Zookeeper) Close() error {
+	z.conn.Close()
+	return nil
+}
+
+// NewZookeeper creates a new ZooKeeper writer.
+func NewZookeeper(cfg config.Zookeeper, logger log.Logger) (*Zookeeper, error) {
+	conn, events, err := zk.Connect(
+		cfg.Servers,
+		time.Duration(cfg.Timeout)*time.Second,
+		zk.WithLogger(zkLogger{logger}),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	z := &Zookeeper{
+		conn:   conn,
+		path:   cfg.Path,
+		port:   cfg.Port,
+		logger: logger,
+	}
+
+	go z.watch(events)
+
+	return z, nil
+}